@@ -0,0 +1,147 @@
+package postgres
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"hash/fnv"
+
+	"gorm.io/gorm"
+)
+
+const migrationLockKey = "postgres:migration_lock"
+
+// ErrAdvisoryLockNotHeld is returned by Unlock when the session didn't hold the lock anymore
+var ErrAdvisoryLockNotHeld = errors.New("postgres: advisory lock not held")
+
+// AdvisoryLock a session level advisory lock, it pins one connection of the pool until it is unlocked
+type AdvisoryLock struct {
+	Key  int64
+	conn *sql.Conn
+	db   *gorm.DB
+}
+
+// AdvisoryLockKey derives an advisory lock key from name
+func AdvisoryLockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// AcquireAdvisoryLock waits until the session level advisory lock key is acquired
+//
+//	lock, err := postgres.AcquireAdvisoryLock(db, postgres.AdvisoryLockKey("billing:leader"))
+//	if err != nil {
+//		return err
+//	}
+//	defer lock.Unlock()
+func AcquireAdvisoryLock(db *gorm.DB, key int64) (*AdvisoryLock, error) {
+	lock, err := pinAdvisoryLock(db, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lock.db.Exec("SELECT pg_advisory_lock(?)", key).Error; err != nil {
+		lock.conn.Close()
+		return nil, err
+	}
+	return lock, nil
+}
+
+// TryAcquireAdvisoryLock acquires the session level advisory lock key if it is available, without waiting
+func TryAcquireAdvisoryLock(db *gorm.DB, key int64) (*AdvisoryLock, bool, error) {
+	lock, err := pinAdvisoryLock(db, key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var acquired bool
+	if err := lock.db.Raw("SELECT pg_try_advisory_lock(?)", key).Scan(&acquired).Error; err != nil || !acquired {
+		lock.conn.Close()
+		return nil, false, err
+	}
+	return lock, true, nil
+}
+
+// WithAdvisoryLock calls fc holding the session level advisory lock key, fc runs on the connection holding the lock
+func WithAdvisoryLock(db *gorm.DB, key int64, fc func(tx *gorm.DB) error) (err error) {
+	lock, err := AcquireAdvisoryLock(db, key)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if unlockErr := lock.Unlock(); err == nil {
+			err = unlockErr
+		}
+	}()
+	return fc(lock.DB())
+}
+
+// DB returns a session on the connection holding the lock
+func (lock *AdvisoryLock) DB() *gorm.DB {
+	return lock.db
+}
+
+// Unlock releases the lock and returns its connection to the pool, when the lock can't be released the connection is discarded
+// instead, so the database releases the locks of its session
+func (lock *AdvisoryLock) Unlock() error {
+	defer lock.conn.Close()
+
+	var unlocked bool
+	err := lock.db.Raw("SELECT pg_advisory_unlock(?)", lock.Key).Scan(&unlocked).Error
+	if err == nil && !unlocked {
+		err = ErrAdvisoryLockNotHeld
+	}
+	if err != nil {
+		lock.conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+	}
+	return err
+}
+
+func pinAdvisoryLock(db *gorm.DB, key int64) (*AdvisoryLock, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := sqlDB.Conn(db.Statement.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := db.Session(&gorm.Session{NewDB: true, Context: db.Statement.Context})
+	tx.Statement.ConnPool = conn
+	return &AdvisoryLock{Key: key, conn: conn, db: tx}, nil
+}
+
+// AdvisoryXactLock waits until the transaction level advisory lock key is acquired, it is released when tx ends
+func AdvisoryXactLock(tx *gorm.DB, key int64) error {
+	if _, ok := tx.Statement.ConnPool.(gorm.TxCommitter); !ok {
+		return gorm.ErrInvalidTransaction
+	}
+	return tx.Exec("SELECT pg_advisory_xact_lock(?)", key).Error
+}
+
+// TryAdvisoryXactLock acquires the transaction level advisory lock key if it is available, without waiting
+func TryAdvisoryXactLock(tx *gorm.DB, key int64) (acquired bool, err error) {
+	if _, ok := tx.Statement.ConnPool.(gorm.TxCommitter); !ok {
+		return false, gorm.ErrInvalidTransaction
+	}
+	err = tx.Raw("SELECT pg_try_advisory_xact_lock(?)", key).Scan(&acquired).Error
+	return
+}
+
+// WithMigrationLock returns a session whose AutoMigrate holds the advisory lock key while migrating,
+// so that concurrent AutoMigrate calls are serialized
+//
+//	postgres.WithMigrationLock(db, postgres.AdvisoryLockKey("auto_migrate")).AutoMigrate(&User{})
+func WithMigrationLock(db *gorm.DB, key int64) *gorm.DB {
+	return db.Set(migrationLockKey, key)
+}
+
+func migrationLockOf(db *gorm.DB) (key int64, ok bool) {
+	if v, found := db.Get(migrationLockKey); found {
+		key, ok = v.(int64)
+	}
+	return
+}
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestAdvisoryLockKey(t *testing.T) {
+	if AdvisoryLockKey("billing:leader") != AdvisoryLockKey("billing:leader") {
+		t.Errorf("expected the same name to derive the same key")
+	}
+	if AdvisoryLockKey("billing:leader") == AdvisoryLockKey("billing:worker") {
+		t.Errorf("expected different names to derive different keys")
+	}
+}
+
+func TestAdvisoryXactLock(t *testing.T) {
+	db := testDB(t, Config{})
+
+	if err := AdvisoryXactLock(db, 1); !errors.Is(err, gorm.ErrInvalidTransaction) {
+		t.Errorf("expected ErrInvalidTransaction outside of a transaction, got %v", err)
+	}
+	if _, err := TryAdvisoryXactLock(db, 1); !errors.Is(err, gorm.ErrInvalidTransaction) {
+		t.Errorf("expected ErrInvalidTransaction outside of a transaction, got %v", err)
+	}
+}
+
+func TestWithMigrationLock(t *testing.T) {
+	db := testDB(t, Config{})
+
+	if _, ok := migrationLockOf(db); ok {
+		t.Errorf("expected no migration lock by default")
+	}
+	key := AdvisoryLockKey("auto_migrate")
+	if got, ok := migrationLockOf(WithMigrationLock(db, key)); !ok || got != key {
+		t.Errorf("expected migration lock %v, got %v", key, got)
+	}
+}
+
+func TestAdvisoryLock_Unlock(t *testing.T) {
+	tests := []struct {
+		name     string
+		unlocked bool
+		wantErr  error
+		wantOpen int
+	}{
+		{name: "it should return the connection to the pool", unlocked: true, wantOpen: 1},
+		{name: "it should discard the connection of a lock not held", unlocked: false, wantErr: ErrAdvisoryLockNotHeld, wantOpen: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, _ := stubDB(t, func(query string) ([]string, [][]driver.Value, error) {
+				return []string{"pg_advisory_unlock"}, [][]driver.Value{{tt.unlocked}}, nil
+			})
+			lock, err := pinAdvisoryLock(db, 1)
+			if err != nil {
+				t.Fatalf("failed to pin a connection, got error %v", err)
+			}
+			if err := lock.Unlock(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Unlock() error = %v, want %v", err, tt.wantErr)
+			}
+			sqlDB, _ := db.DB()
+			if open := sqlDB.Stats().OpenConnections; open != tt.wantOpen {
+				t.Errorf("expected %v open connections, got %v", tt.wantOpen, open)
+			}
+		})
+	}
+}
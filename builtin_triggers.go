@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"time"
+)
+
+const (
+	updatedAtFunctionName = "gorm_set_updated_at"
+	auditFunctionName     = "gorm_audit_row"
+)
+
+// AuditRecord a row change recorded by AuditTrigger, the old and new rows are stored as JSONB
+type AuditRecord struct {
+	ID          uint64 `gorm:"primaryKey"`
+	TableSchema string `gorm:"index:idx_gorm_audit_records_table"`
+	Table       string `gorm:"column:table_name;index:idx_gorm_audit_records_table"`
+	// Operation is INSERT, UPDATE or DELETE
+	Operation string
+	OldRow    []byte `gorm:"type:jsonb"`
+	NewRow    []byte `gorm:"type:jsonb"`
+	ChangedAt time.Time
+	ChangedBy string
+}
+
+// TableName the table of the audit records, shared by the tables of all audited models
+func (AuditRecord) TableName() string {
+	return "gorm_audit_records"
+}
+
+// UpdatedAtTrigger returns a trigger setting the timestamp column, `updated_at` by default, to the current time on every update,
+// including the updates made with raw SQL
+//
+//	func (Order) Triggers() []postgres.Trigger {
+//		return []postgres.Trigger{postgres.UpdatedAtTrigger(""), postgres.AuditTrigger()}
+//	}
+func UpdatedAtTrigger(column string) Trigger {
+	if column == "" {
+		column = "updated_at"
+	}
+	return Trigger{
+		Name:   updatedAtFunctionName,
+		Timing: TriggerBefore,
+		Events: []TriggerEvent{TriggerUpdate},
+		Function: Function{
+			Name: updatedAtFunctionName,
+			Body: `
+BEGIN
+	NEW := jsonb_populate_record(NEW, jsonb_build_object(TG_ARGV[0], now()));
+	RETURN NEW;
+END;
+`,
+		},
+		Arguments: []string{column},
+	}
+}
+
+// AuditTrigger returns a trigger recording every inserted, updated and deleted row as an AuditRecord,
+// the `gorm_audit_records` table is created by AutoMigrate with the trigger, the function keeps the `search_path` it is created
+// with, so the records are inserted into the table of its schema whatever the `search_path` of the audited statements, the
+// audit functions created without it are replaced by AutoMigrate
+func AuditTrigger() Trigger {
+	return Trigger{
+		Name:   auditFunctionName,
+		Timing: TriggerAfter,
+		Events: []TriggerEvent{TriggerInsert, TriggerUpdate, TriggerDelete},
+		Function: Function{
+			Name:    auditFunctionName,
+			Options: "SET search_path FROM CURRENT",
+			Body: `
+BEGIN
+	INSERT INTO gorm_audit_records (table_schema, table_name, operation, old_row, new_row, changed_at, changed_by)
+	VALUES (
+		TG_TABLE_SCHEMA, TG_TABLE_NAME, TG_OP,
+		CASE WHEN TG_OP IN ('UPDATE', 'DELETE') THEN to_jsonb(OLD) END,
+		CASE WHEN TG_OP IN ('INSERT', 'UPDATE') THEN to_jsonb(NEW) END,
+		now(), current_user
+	);
+	RETURN NULL;
+END;
+`,
+		},
+	}
+}
+
+// createAuditTable creates the table of the audit records when trigger is an audit trigger
+func (m Migrator) createAuditTable(trigger Trigger) error {
+	if trigger.Function.Name != auditFunctionName || m.HasTable(&AuditRecord{}) {
+		return nil
+	}
+	return m.CreateTable(&AuditRecord{})
+}
@@ -0,0 +1,142 @@
+package postgres
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+type auditedOrder struct {
+	ID uint64
+}
+
+func (auditedOrder) Triggers() []Trigger {
+	return []Trigger{UpdatedAtTrigger(""), AuditTrigger()}
+}
+
+func TestBuiltinTriggers(t *testing.T) {
+	s, err := schema.Parse(&auditedOrder{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("failed to parse schema, got error %v", err)
+	}
+	dialector := Dialector{Config: &Config{}}
+	db := &gorm.DB{Config: &gorm.Config{Dialector: dialector}}
+	db.Statement = &gorm.Statement{DB: db}
+	m := Migrator{migrator.Migrator{Config: migrator.Config{DB: db, Dialector: dialector}}}
+	stmt := &gorm.Statement{DB: db, Schema: s, Table: s.Table}
+
+	tests := []struct {
+		name    string
+		trigger Trigger
+		want    string
+	}{
+		{
+			name:    "it should set updated_at before updates",
+			trigger: UpdatedAtTrigger(""),
+			want:    `TRIGGER "gorm_set_updated_at" BEFORE UPDATE ON "audited_orders" FOR EACH ROW EXECUTE FUNCTION "gorm_set_updated_at"('updated_at')`,
+		},
+		{
+			name:    "it should set the given column",
+			trigger: UpdatedAtTrigger("modified_at"),
+			want:    `TRIGGER "gorm_set_updated_at" BEFORE UPDATE ON "audited_orders" FOR EACH ROW EXECUTE FUNCTION "gorm_set_updated_at"('modified_at')`,
+		},
+		{
+			name:    "it should audit the rows after every change",
+			trigger: AuditTrigger(),
+			want:    `TRIGGER "gorm_audit_row" AFTER INSERT OR UPDATE OR DELETE ON "audited_orders" FOR EACH ROW EXECUTE FUNCTION "gorm_audit_row"()`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.triggerSQL(stmt, tt.trigger); got != tt.want {
+				t.Errorf("triggerSQL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditRecord_schema(t *testing.T) {
+	s, err := schema.Parse(&AuditRecord{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("failed to parse schema, got error %v", err)
+	}
+	for _, column := range []string{"table_schema", "table_name", "operation", "old_row", "new_row", "changed_at", "changed_by"} {
+		if _, ok := s.FieldsByDBName[column]; !ok {
+			t.Errorf("expected the column %v written by the audit function", column)
+		}
+	}
+	if s.Table != "gorm_audit_records" {
+		t.Errorf("expected the table written by the audit function, got %v", s.Table)
+	}
+}
+
+func TestMigrator_CreateFunction_audit(t *testing.T) {
+	recorder := &planRecorder{Interface: logger.Discard, plan: &migrationPlan{}}
+	m := dryRunDB(t).Session(&gorm.Session{Logger: recorder}).Migrator().(Migrator)
+	function := AuditTrigger().Function
+	if err := m.CreateFunction(function); err != nil {
+		t.Fatalf("CreateFunction() error = %v", err)
+	}
+
+	want := `CREATE OR REPLACE FUNCTION "gorm_audit_row"() RETURNS trigger LANGUAGE plpgsql SET search_path FROM CURRENT AS $function$` +
+		function.Body + `$function$`
+	if got := recorder.plan.sql; len(got) != 1 || got[0] != want {
+		t.Errorf("CreateFunction() executed %q, want %q", got, want)
+	}
+}
+
+type auditedInvoice struct {
+	ID uint64
+}
+
+func (auditedInvoice) Triggers() []Trigger {
+	return []Trigger{AuditTrigger()}
+}
+
+func TestMigrator_migrateTriggers_audit(t *testing.T) {
+	function := AuditTrigger().Function
+	tests := []struct {
+		name   string
+		config string
+		want   []string
+	}{
+		{
+			name: "it should replace an existing audit function created without its search_path",
+			want: []string{
+				`CREATE OR REPLACE FUNCTION "gorm_audit_row"() RETURNS trigger LANGUAGE plpgsql SET search_path FROM CURRENT AS $function$` +
+					function.Body + `$function$`,
+			},
+		},
+		{name: "it should keep an existing audit function keeping its search_path", config: "search_path=app, public"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, connector := stubDB(t, func(query string) ([]string, [][]driver.Value, error) {
+				if strings.Contains(query, "pg_get_function_arguments") {
+					return []string{"source", "arguments", "returns", "language", "security_definer", "volatility", "strict", "config"},
+						[][]driver.Value{{function.Body, "", "trigger", "plpgsql", false, "v", false, tt.config}}, nil
+				}
+				return nil, nil, nil
+			})
+			if err := db.Migrator().(Migrator).migrateTriggers(&auditedInvoice{}, true); err != nil {
+				t.Fatalf("migrateTriggers() error = %v", err)
+			}
+			var replaced []string
+			for _, statement := range connector.statements() {
+				if strings.HasPrefix(statement, "CREATE OR REPLACE FUNCTION") {
+					replaced = append(replaced, statement)
+				}
+			}
+			if !reflect.DeepEqual(replaced, tt.want) {
+				t.Errorf("migrateTriggers() executed %q, want %q", replaced, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const bulkUpdateAlias = "v"
+
+// BulkUpdate updates the columns of the rows of values, a slice of models, in a single `UPDATE ... FROM (VALUES ...)`
+// statement matching the rows by primary key, all the columns but the primary keys and the creation times by default,
+// the values of the models are written as they are
+//
+//	postgres.BulkUpdate(db, users, "name", "age")
+//	// UPDATE "users" SET "name"="v"."name","age"="v"."age" FROM (VALUES ($1::bigint,$2::text,$3::bigint),...) AS "v"("id","name","age") WHERE "users"."id" = "v"."id"
+func BulkUpdate(db *gorm.DB, values interface{}, columns ...string) *gorm.DB {
+	if rows := reflect.Indirect(reflect.ValueOf(values)); (rows.Kind() == reflect.Slice || rows.Kind() == reflect.Array) && rows.Len() == 0 {
+		return db
+	}
+	return db.Exec("?", bulkUpdate{Values: values, Columns: columns})
+}
+
+// bulkUpdate the `UPDATE ... FROM (VALUES ...)` statement of BulkUpdate
+type bulkUpdate struct {
+	Values  interface{}
+	Columns []string
+}
+
+// Build builds the `UPDATE` statement
+func (update bulkUpdate) Build(builder clause.Builder) {
+	stmt, ok := builder.(*gorm.Statement)
+	if !ok {
+		builder.AddError(errors.New("failed to build a bulk update outside of a statement"))
+		return
+	}
+
+	rows := reflect.Indirect(reflect.ValueOf(update.Values))
+	if rows.Kind() != reflect.Slice && rows.Kind() != reflect.Array {
+		builder.AddError(fmt.Errorf("failed to bulk update %T, a slice of models is required", update.Values))
+		return
+	}
+	sourceStmt := &gorm.Statement{DB: stmt.DB}
+	if err := sourceStmt.Parse(update.Values); err != nil {
+		builder.AddError(err)
+		return
+	}
+	s := sourceStmt.Schema
+
+	table := stmt.Table
+	if table == "" {
+		table = s.Table
+	}
+	where := primaryKeysJoin(s, table, bulkUpdateAlias)
+	if where == nil {
+		builder.AddError(fmt.Errorf("failed to bulk update %s without primary keys", table))
+		return
+	}
+
+	columns := update.Columns
+	if len(columns) == 0 {
+		for _, column := range valuesColumnsOf(s) {
+			if field := s.FieldsByDBName[column]; !field.PrimaryKey && field.AutoCreateTime == 0 {
+				columns = append(columns, column)
+			}
+		}
+	}
+	if len(columns) == 0 {
+		builder.AddError(fmt.Errorf("failed to bulk update %s without columns", table))
+		return
+	}
+
+	set := make(clause.Set, 0, len(columns))
+	valuesColumns := append([]string{}, s.PrimaryFieldDBNames...)
+	for _, column := range columns {
+		field := s.LookUpField(column)
+		if field == nil || field.DBName == "" {
+			builder.AddError(fmt.Errorf("failed to find the column %s of %s", column, s.Name))
+			return
+		}
+		set = append(set, clause.Assignment{Column: clause.Column{Name: field.DBName}, Value: clause.Column{Table: bulkUpdateAlias, Name: field.DBName}})
+		if !field.PrimaryKey {
+			valuesColumns = append(valuesColumns, field.DBName)
+		}
+	}
+
+	builder.WriteString("UPDATE ")
+	builder.WriteQuoted(clause.Table{Name: table})
+	builder.WriteString(" SET ")
+	set.Build(builder)
+	builder.WriteString(" FROM ")
+	if err := writeValues(stmt, s, rows, valuesColumns, bulkUpdateAlias); err != nil {
+		builder.AddError(err)
+		return
+	}
+	builder.WriteString(" WHERE ")
+	where.Build(builder)
+}
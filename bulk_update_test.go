@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type bulkUser struct {
+	ID        uint64
+	Name      string
+	Age       int
+	CreatedAt time.Time
+}
+
+func TestBulkUpdate(t *testing.T) {
+	tx := dryRunDB(t)
+	users := []bulkUser{{ID: 1, Name: "jinzhu", Age: 18}, {ID: 2, Name: "gorm", Age: 10}}
+
+	tests := []struct {
+		name     string
+		db       *gorm.DB
+		columns  []string
+		want     string
+		wantVars int
+	}{
+		{
+			name:     "it should update all the columns but the primary keys and the creation times",
+			db:       tx,
+			want:     `UPDATE "bulk_users" SET "name"="v"."name","age"="v"."age" FROM (VALUES ($1::bigint,$2::text,$3::bigint),($4::bigint,$5::text,$6::bigint)) AS "v"("id","name","age") WHERE "bulk_users"."id" = "v"."id"`,
+			wantVars: 6,
+		},
+		{
+			name:     "it should update the columns of the table",
+			db:       tx.Table("archived_users"),
+			columns:  []string{"Age"},
+			want:     `UPDATE "archived_users" SET "age"="v"."age" FROM (VALUES ($1::bigint,$2::bigint),($3::bigint,$4::bigint)) AS "v"("id","age") WHERE "archived_users"."id" = "v"."id"`,
+			wantVars: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt := BulkUpdate(tt.db, users, tt.columns...).Statement
+			if stmt.SQL.String() != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, stmt.SQL.String())
+			}
+			if len(stmt.Vars) != tt.wantVars {
+				t.Errorf("expected %d vars, got %v", tt.wantVars, stmt.Vars)
+			}
+		})
+	}
+
+	if err := BulkUpdate(tx, users, "unknown").Error; err == nil {
+		t.Errorf("expected an error updating an unknown column")
+	}
+	if result := BulkUpdate(tx, []bulkUser{}); result != tx {
+		t.Errorf("expected no statement for no rows")
+	}
+}
@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// TableCommenter a model with a table comment, applied by AutoMigrate with `COMMENT ON TABLE`
+//
+//	func (User) TableComment() string {
+//		return "registered users"
+//	}
+//
+// the table comment can also be declared with the tag `tableComment` on any field, e.g. `gorm:"primaryKey;tableComment:registered users"`
+type TableCommenter interface {
+	TableComment() string
+}
+
+// ConstraintCommenter a model with constraint comments keyed by constraint name, applied by AutoMigrate with `COMMENT ON CONSTRAINT`
+//
+//	func (User) ConstraintComments() map[string]string {
+//		return map[string]string{"chk_users_age": "users must be adults"}
+//	}
+type ConstraintCommenter interface {
+	ConstraintComments() map[string]string
+}
+
+const (
+	relationCommentSQL   = "SELECT COALESCE(obj_description(c.oid, 'pg_class'), '') FROM pg_catalog.pg_class c JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = ? AND c.relname = ?"
+	constraintCommentSQL = "SELECT COALESCE(obj_description(con.oid, 'pg_constraint'), '') FROM pg_catalog.pg_constraint con JOIN pg_catalog.pg_class c ON c.oid = con.conrelid JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = ? AND c.relname = ? AND con.conname = ?"
+)
+
+// tableCommentOf returns the table comment of a model, from the TableCommenter interface or the `tableComment` tag
+func tableCommentOf(value interface{}, s *schema.Schema) (string, bool) {
+	if commenter, ok := value.(TableCommenter); ok {
+		return commenter.TableComment(), true
+	}
+	if s == nil {
+		return "", false
+	}
+	for _, field := range s.Fields {
+		if comment, ok := field.TagSettings["TABLECOMMENT"]; ok {
+			return comment, true
+		}
+	}
+	return "", false
+}
+
+// TableComment returns the comment of value's table, empty if it has none
+func (m Migrator) TableComment(value interface{}) (comment string, err error) {
+	err = m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		currentSchema, curTable := m.CurrentSchema(stmt, stmt.Table)
+		return m.queryRaw(relationCommentSQL, currentSchema, curTable).Scan(&comment).Error
+	})
+	return
+}
+
+// migrateComments applies the table, index and constraint comments of value,
+// with diff only the ones differing from the database are applied, and only on existing indexes and constraints
+func (m Migrator) migrateComments(value interface{}, diff bool) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		currentSchema, curTable := m.CurrentSchema(stmt, stmt.Table)
+
+		if tableComment, ok := tableCommentOf(value, stmt.Schema); ok {
+			var current string
+			if diff {
+				m.queryRaw(relationCommentSQL, currentSchema, curTable).Scan(&current)
+			}
+			if comment := unquoteComment(tableComment); comment != current {
+				if err := m.DB.Exec("COMMENT ON TABLE ? IS ?", m.CurrentTable(stmt), m.commentExpr(comment)).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		if diff && stmt.Schema != nil {
+			for _, idx := range stmt.Schema.ParseIndexes() {
+				if idx.Comment == "" || !m.HasIndex(value, idx.Name) {
+					continue
+				}
+
+				var current string
+				m.queryRaw(relationCommentSQL, currentSchema, idx.Name).Scan(&current)
+				if comment := unquoteComment(idx.Comment); comment != current {
+					if err := m.commentIndex(stmt, idx.Name, comment); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		if commenter, ok := value.(ConstraintCommenter); ok {
+			for name, comment := range commenter.ConstraintComments() {
+				var current string
+				if diff {
+					if !m.HasConstraint(value, name) {
+						continue
+					}
+					m.queryRaw(constraintCommentSQL, currentSchema, curTable, name).Scan(&current)
+				}
+				if comment = unquoteComment(comment); comment != current {
+					if err := m.DB.Exec(
+						"COMMENT ON CONSTRAINT ? ON ? IS ?", clause.Column{Name: name}, m.CurrentTable(stmt), m.commentExpr(comment),
+					).Error; err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (m Migrator) commentIndex(stmt *gorm.Statement, name, comment string) error {
+	return m.DB.Exec("COMMENT ON INDEX ? IS ?", m.qualifiedName(stmt, name), m.commentExpr(comment)).Error
+}
+
+// commentExpr inlines comment as a string literal, `COMMENT ON` doesn't accept bind parameters
+func (m Migrator) commentExpr(comment string) clause.Expr {
+	return gorm.Expr(m.Migrator.Dialector.Explain("$1", comment))
+}
+
+// unquoteComment strips one pair of matching quotes around comment, e.g. from the tag `comment:'user name'`
+func unquoteComment(comment string) string {
+	if len(comment) >= 2 && (comment[0] == '\'' || comment[0] == '"') && comment[len(comment)-1] == comment[0] {
+		return comment[1 : len(comment)-1]
+	}
+	return comment
+}
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"sync"
+	"testing"
+
+	"gorm.io/gorm/schema"
+)
+
+type commentedUser struct {
+	ID   uint64 `gorm:"primaryKey;tableComment:registered users"`
+	Name string
+}
+
+type commenterUser struct {
+	ID uint64 `gorm:"primaryKey;tableComment:from the tag"`
+}
+
+func (commenterUser) TableComment() string {
+	return "from the interface"
+}
+
+type uncommentedUser struct {
+	ID uint64 `gorm:"primaryKey;comment:the id"`
+}
+
+func Test_unquoteComment(t *testing.T) {
+	tests := []struct {
+		name    string
+		comment string
+		want    string
+	}{
+		{name: "it should keep unquoted comments", comment: "user name", want: "user name"},
+		{name: "it should strip single quotes", comment: "'user name'", want: "user name"},
+		{name: "it should strip double quotes", comment: `"user name"`, want: "user name"},
+		{name: "it should strip only one pair of quotes", comment: "''quoted''", want: "'quoted'"},
+		{name: "it should keep unmatched quotes", comment: `'user name"`, want: `'user name"`},
+		{name: "it should keep inner quotes", comment: "user's name", want: "user's name"},
+		{name: "it should keep a single quote", comment: "'", want: "'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unquoteComment(tt.comment); got != tt.want {
+				t.Errorf("unquoteComment() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_tableCommentOf(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  interface{}
+		want   string
+		wantOk bool
+	}{
+		{name: "it should read the tableComment tag", value: &commentedUser{}, want: "registered users", wantOk: true},
+		{name: "it should prefer the TableCommenter interface", value: &commenterUser{}, want: "from the interface", wantOk: true},
+		{name: "it should ignore column comments", value: &uncommentedUser{}, wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := schema.Parse(tt.value, &sync.Map{}, schema.NamingStrategy{})
+			if err != nil {
+				t.Fatalf("schema.Parse() error = %v", err)
+			}
+			got, ok := tableCommentOf(tt.value, s)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("tableCommentOf() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
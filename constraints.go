@@ -0,0 +1,222 @@
+package postgres
+
+import (
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ConstraintType the type of a table constraint
+type ConstraintType string
+
+const (
+	ConstraintPrimaryKey ConstraintType = "PRIMARY KEY"
+	ConstraintForeignKey ConstraintType = "FOREIGN KEY"
+	ConstraintUnique     ConstraintType = "UNIQUE"
+	ConstraintCheck      ConstraintType = "CHECK"
+	ConstraintExclusion  ConstraintType = "EXCLUDE"
+)
+
+// ConstraintDefinition a constraint of a table, with its definition as deparsed by the database
+type ConstraintDefinition struct {
+	Name       string
+	Type       ConstraintType
+	Definition string
+	Deferrable bool
+	Deferred   bool
+	// Validated is false for constraints added with NOT VALID and not validated yet
+	Validated bool
+	// Match, OnDelete and OnUpdate the options of a foreign key, e.g. SIMPLE and NO ACTION by default
+	Match    string
+	OnDelete string
+	OnUpdate string
+}
+
+// ForeignKeyOptions the Postgres specific options of a foreign key, set with the keys `Deferrable`, `InitiallyDeferred`,
+// `Match` and `NotValid` of the constraint tag, after its name or its `OnDelete` / `OnUpdate` keys
+//
+//	UserID uint
+//	User   User `gorm:"constraint:OnDelete:CASCADE,Deferrable,InitiallyDeferred,Match:FULL"`
+type ForeignKeyOptions struct {
+	Deferrable        bool
+	InitiallyDeferred bool
+	// Match is FULL or SIMPLE, the default
+	Match string
+	// NotValid adds the foreign key without checking the existing rows, only new and updated rows are checked
+	NotValid bool
+}
+
+var (
+	// foreignKeyMatchTypes the match types of pg_constraint.confmatchtype
+	foreignKeyMatchTypes = map[string]string{"f": "FULL", "p": "PARTIAL", "s": "SIMPLE"}
+	// foreignKeyActions the actions of pg_constraint.confdeltype and confupdtype
+	foreignKeyActions = map[string]string{"a": "NO ACTION", "r": "RESTRICT", "c": "CASCADE", "n": "SET NULL", "d": "SET DEFAULT"}
+
+	constraintDefaultsPattern  = regexp.MustCompile(`(?i)\s+(MATCH\s+SIMPLE|NOT\s+DEFERRABLE|INITIALLY\s+IMMEDIATE|ON\s+(DELETE|UPDATE)\s+NO\s+ACTION|NOT\s+VALID)\b`)
+	constraintQualifierPattern = regexp.MustCompile(`(?i)\b[a-z_][a-z0-9_$]*\.`)
+)
+
+// foreignKeyOptionsOf returns the foreign key options of constraint's tag
+func foreignKeyOptionsOf(constraint *schema.Constraint) (options ForeignKeyOptions) {
+	if constraint.Field == nil {
+		return
+	}
+	settings := schema.ParseTagSetting(constraint.Field.TagSettings["CONSTRAINT"], ",")
+	_, options.InitiallyDeferred = settings["INITIALLYDEFERRED"]
+	_, options.Deferrable = settings["DEFERRABLE"]
+	options.Deferrable = options.Deferrable || options.InitiallyDeferred
+	options.Match = strings.ToUpper(strings.TrimSpace(settings["MATCH"]))
+	_, options.NotValid = settings["NOTVALID"]
+	return
+}
+
+// hasOptions returns whether options change the foreign key gorm would create
+func (options ForeignKeyOptions) hasOptions() bool {
+	return options.Deferrable || options.Match != "" || options.NotValid
+}
+
+// clauses returns the clauses following `REFERENCES` of constraint, with options around `ON DELETE` and `ON UPDATE`
+func (options ForeignKeyOptions) clauses(constraint *schema.Constraint) string {
+	var sql string
+	if options.Match != "" {
+		sql += " MATCH " + options.Match
+	}
+	if constraint.OnDelete != "" {
+		sql += " ON DELETE " + constraint.OnDelete
+	}
+	if constraint.OnUpdate != "" {
+		sql += " ON UPDATE " + constraint.OnUpdate
+	}
+	if options.Deferrable {
+		sql += " DEFERRABLE"
+		if options.InitiallyDeferred {
+			sql += " INITIALLY DEFERRED"
+		}
+	}
+	return sql
+}
+
+// buildForeignKey builds constraint like schema.Constraint.Build, with the foreign key options in the order of the Postgres grammar
+func buildForeignKey(constraint *schema.Constraint, options ForeignKeyOptions) (string, []interface{}) {
+	_, vars := constraint.Build()
+	return "CONSTRAINT ? FOREIGN KEY ? REFERENCES ??" + options.clauses(constraint), vars
+}
+
+// foreignKeyChanged returns whether the match type, the actions or the deferrability of the foreign key current differ from
+// the ones declared by constraint, the catalog values are compared as the database rewrites the deparsed definition
+func foreignKeyChanged(current ConstraintDefinition, constraint *schema.Constraint) bool {
+	options := foreignKeyOptionsOf(constraint)
+	match := options.Match
+	if match == "" {
+		match = "SIMPLE"
+	}
+	action := func(action string) string {
+		if action = strings.Join(strings.Fields(strings.ToUpper(action)), " "); action == "" {
+			return "NO ACTION"
+		}
+		return action
+	}
+	return current.Match != match || current.OnDelete != action(constraint.OnDelete) || current.OnUpdate != action(constraint.OnUpdate) ||
+		current.Deferrable != options.Deferrable || current.Deferred != options.InitiallyDeferred
+}
+
+// normalizeConstraintDefinition normalizes a constraint definition to compare it with its deparsed form in the catalog,
+// the default options, NOT VALID, quotes and schema qualifiers outside of string literals are dropped too
+func normalizeConstraintDefinition(definition string) string {
+	definition = mapOutsideLiterals(definition, func(s string) string {
+		s = constraintDefaultsPattern.ReplaceAllString(s, "")
+		return constraintQualifierPattern.ReplaceAllString(strings.ReplaceAll(s, `"`, ""), "")
+	})
+	return normalizeExpression(definition)
+}
+
+// GetConstraints returns the constraints of value's table with their definitions
+func (m Migrator) GetConstraints(value interface{}) (constraints []ConstraintDefinition, err error) {
+	err = m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		currentSchema, curTable := m.CurrentSchema(stmt, stmt.Table)
+		rows, err := m.queryRaw(
+			"SELECT con.conname, con.contype, pg_get_constraintdef(con.oid), con.condeferrable, con.condeferred, con.convalidated, con.confmatchtype::text, con.confdeltype::text, con.confupdtype::text FROM pg_catalog.pg_constraint con JOIN pg_catalog.pg_class c ON c.oid = con.conrelid JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = ? AND c.relname = ? AND con.contype IN ('p', 'f', 'u', 'c', 'x') ORDER BY con.conname",
+			currentSchema, curTable,
+		).Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				constraint                         ConstraintDefinition
+				contype, match, onDelete, onUpdate string
+			)
+			if err := rows.Scan(
+				&constraint.Name, &contype, &constraint.Definition, &constraint.Deferrable, &constraint.Deferred, &constraint.Validated, &match, &onDelete, &onUpdate,
+			); err != nil {
+				return err
+			}
+			switch contype {
+			case "p":
+				constraint.Type = ConstraintPrimaryKey
+			case "f":
+				constraint.Type = ConstraintForeignKey
+				constraint.Match, constraint.OnDelete, constraint.OnUpdate = foreignKeyMatchTypes[match], foreignKeyActions[onDelete], foreignKeyActions[onUpdate]
+			case "u":
+				constraint.Type = ConstraintUnique
+			case "c":
+				constraint.Type = ConstraintCheck
+			case "x":
+				constraint.Type = ConstraintExclusion
+			}
+			constraints = append(constraints, constraint)
+		}
+		return rows.Err()
+	})
+	return
+}
+
+// foreignKeysWithOptions returns the foreign keys created with value's table, when one of them has foreign key options
+func (m Migrator) foreignKeysWithOptions(stmt *gorm.Statement) (constraints []*schema.Constraint) {
+	if stmt.Schema == nil || m.DB.DisableForeignKeyConstraintWhenMigrating || m.DB.IgnoreRelationshipsWhenMigrating {
+		return nil
+	}
+
+	var withOptions bool
+	for _, rel := range stmt.Schema.Relationships.Relations {
+		if rel.Field.IgnoreMigration {
+			continue
+		}
+		if constraint := rel.ParseConstraint(); constraint != nil && constraint.Schema == stmt.Schema {
+			constraints = append(constraints, constraint)
+			withOptions = withOptions || foreignKeyOptionsOf(constraint).hasOptions()
+		}
+	}
+	if !withOptions {
+		return nil
+	}
+	return constraints
+}
+
+// migrateConstraint creates the constraint name of value when it doesn't exist, and recreates it when changed reports the
+// existing one changed, a nil changed only creates the missing constraint
+func (m Migrator) migrateConstraint(value interface{}, name string, existing []ConstraintDefinition, changed func(ConstraintDefinition) bool) error {
+	for _, current := range existing {
+		if current.Name != name {
+			continue
+		}
+		if changed == nil || !changed(current) {
+			return nil
+		}
+		if err := m.DropConstraint(value, name); err != nil {
+			return err
+		}
+		break
+	}
+	return m.CreateConstraint(value, name)
+}
+
+// validateConstraint validates the constraint name of value added with NOT VALID
+func (m Migrator) validateConstraint(table interface{}, name string) error {
+	return m.DB.Exec("ALTER TABLE ? VALIDATE CONSTRAINT ?", table, clause.Column{Name: name}).Error
+}
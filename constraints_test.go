@@ -0,0 +1,161 @@
+package postgres
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+type constraintCompany struct {
+	ID uint64
+}
+
+type constraintUser struct {
+	ID        uint64
+	CompanyID uint64
+	Company   constraintCompany `gorm:"constraint:OnDelete:CASCADE,Deferrable,InitiallyDeferred,Match:full,NotValid"`
+	ManagerID *uint64
+	Manager   *constraintUser `gorm:"constraint:OnUpdate:NO ACTION"`
+}
+
+func parseConstraint(t *testing.T, field string) *schema.Constraint {
+	s, err := schema.Parse(&constraintUser{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("failed to parse schema, got error %v", err)
+	}
+	constraint := s.Relationships.Relations[field].ParseConstraint()
+	if constraint == nil {
+		t.Fatalf("failed to parse the constraint of %s", field)
+	}
+	return constraint
+}
+
+func Test_foreignKeyOptionsOf(t *testing.T) {
+	tests := []struct {
+		name  string
+		field string
+		want  ForeignKeyOptions
+	}{
+		{name: "it should parse the options of the constraint tag", field: "Company", want: ForeignKeyOptions{Deferrable: true, InitiallyDeferred: true, Match: "FULL", NotValid: true}},
+		{name: "it should have no options without the tag keys", field: "Manager", want: ForeignKeyOptions{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := foreignKeyOptionsOf(parseConstraint(t, tt.field)); got != tt.want {
+				t.Errorf("foreignKeyOptionsOf() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_buildForeignKey(t *testing.T) {
+	constraint := parseConstraint(t, "Company")
+	sql, vars := buildForeignKey(constraint, foreignKeyOptionsOf(constraint))
+	if want := "CONSTRAINT ? FOREIGN KEY ? REFERENCES ?? MATCH FULL ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED"; sql != want {
+		t.Errorf("buildForeignKey() = %v, want %v", sql, want)
+	}
+	if len(vars) != 4 {
+		t.Errorf("buildForeignKey() vars = %v, want the vars of schema.Constraint.Build", vars)
+	}
+}
+
+func Test_foreignKeyChanged(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   string
+		current ConstraintDefinition
+		want    bool
+	}{
+		{
+			name:    "it should match the catalog options",
+			field:   "Company",
+			current: ConstraintDefinition{Match: "FULL", OnDelete: "CASCADE", OnUpdate: "NO ACTION", Deferrable: true, Deferred: true},
+		},
+		{
+			name:    "it should match the default options",
+			field:   "Manager",
+			current: ConstraintDefinition{Match: "SIMPLE", OnDelete: "NO ACTION", OnUpdate: "NO ACTION"},
+		},
+		{
+			name:    "it should detect a changed match type",
+			field:   "Company",
+			current: ConstraintDefinition{Match: "SIMPLE", OnDelete: "CASCADE", OnUpdate: "NO ACTION", Deferrable: true, Deferred: true},
+			want:    true,
+		},
+		{
+			name:    "it should detect changed actions",
+			field:   "Manager",
+			current: ConstraintDefinition{Match: "SIMPLE", OnDelete: "NO ACTION", OnUpdate: "CASCADE"},
+			want:    true,
+		},
+		{
+			name:    "it should detect a changed deferrability",
+			field:   "Company",
+			current: ConstraintDefinition{Match: "FULL", OnDelete: "CASCADE", OnUpdate: "NO ACTION", Deferrable: true},
+			want:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := foreignKeyChanged(tt.current, parseConstraint(t, tt.field)); got != tt.want {
+				t.Errorf("foreignKeyChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type constraintProduct struct {
+	ID     uint64
+	Status string  `gorm:"check:chk_status,status IN ('a', 'b')"`
+	Stock  int     `gorm:"check:chk_stock,stock BETWEEN 1 AND 9"`
+	Price  float64 `gorm:"check:chk_price,price::numeric > 0"`
+}
+
+func TestMigrator_migrateConstraint_check(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []ConstraintDefinition
+		want     []string
+	}{
+		{
+			name: "it should keep the checks rewritten by the database",
+			existing: []ConstraintDefinition{
+				{Name: "chk_status", Type: ConstraintCheck, Definition: "CHECK ((status = ANY (ARRAY['a'::text, 'b'::text])))"},
+				{Name: "chk_stock", Type: ConstraintCheck, Definition: "CHECK (((stock >= 1) AND (stock <= 9)))"},
+				{Name: "chk_price", Type: ConstraintCheck, Definition: "CHECK (((price)::numeric > (0)::numeric))"},
+			},
+		},
+		{
+			name: "it should create the missing checks",
+			want: []string{
+				`ALTER TABLE "constraint_products" ADD CONSTRAINT "chk_status" CHECK (status IN ('a', 'b'))`,
+				`ALTER TABLE "constraint_products" ADD CONSTRAINT "chk_stock" CHECK (stock BETWEEN 1 AND 9)`,
+				`ALTER TABLE "constraint_products" ADD CONSTRAINT "chk_price" CHECK (price::numeric > 0)`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &planRecorder{Interface: logger.Discard, plan: &migrationPlan{}}
+			m := dryRunDB(t).Session(&gorm.Session{Logger: recorder}).Migrator().(Migrator)
+			for _, name := range []string{"chk_status", "chk_stock", "chk_price"} {
+				if err := m.migrateConstraint(&constraintProduct{}, name, tt.existing, nil); err != nil {
+					t.Fatalf("migrateConstraint() error = %v", err)
+				}
+			}
+			if got := recorder.plan.sql; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("migrateConstraint() executed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_normalizeConstraintDefinition_check(t *testing.T) {
+	if got, want := normalizeConstraintDefinition("CHECK ((age > 18))"), normalizeConstraintDefinition("CHECK (age > 18)"); got != want {
+		t.Errorf("normalizeConstraintDefinition() = %v, want %v", got, want)
+	}
+}
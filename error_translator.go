@@ -2,18 +2,23 @@ package postgres
 
 import (
 	"encoding/json"
+	"errors"
 
 	"gorm.io/gorm"
 
 	"github.com/jackc/pgx/v5/pgconn"
 )
 
+// ErrExclusionConstraintViolated occurs when there is an exclusion constraint violation
+var ErrExclusionConstraintViolated = errors.New("violates exclusion constraint")
+
 // The error codes to map PostgreSQL errors to gorm errors, here is the PostgreSQL error codes reference https://www.postgresql.org/docs/current/errcodes-appendix.html.
 var errCodes = map[string]error{
 	"23505": gorm.ErrDuplicatedKey,
 	"23503": gorm.ErrForeignKeyViolated,
 	"42703": gorm.ErrInvalidField,
 	"23514": gorm.ErrCheckConstraintViolated,
+	"23P01": ErrExclusionConstraintViolated,
 }
 
 type ErrMessage struct {
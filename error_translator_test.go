@@ -41,6 +41,11 @@ func TestDialector_Translate(t *testing.T) {
 			args: args{err: &pgconn.PgError{Code: "23514"}},
 			want: gorm.ErrCheckConstraintViolated,
 		},
+		{
+			name: "it should return ErrExclusionConstraintViolated error if the status code is 23P01",
+			args: args{err: &pgconn.PgError{Code: "23P01"}},
+			want: ErrExclusionConstraintViolated,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,159 @@
+package postgres
+
+import (
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ExclusionElement an element of an exclusion constraint, the column or the expression compared by Operator
+type ExclusionElement struct {
+	Column     string
+	Expression string
+	Operator   string
+}
+
+// ExclusionConstraint an exclusion constraint, two rows conflict when all of its elements compare true
+type ExclusionConstraint struct {
+	Name string
+	// Using is the index method, defaults to gist
+	Using    string
+	Elements []ExclusionElement
+	// Where restricts the constraint to the rows matching the predicate
+	Where             string
+	Deferrable        bool
+	InitiallyDeferred bool
+}
+
+// ExclusionConstrained a model whose exclusion constraints are created by AutoMigrate,
+// `btree_gist` is created when a gist constraint compares scalar columns with `=` or `<>`
+//
+//	func (Booking) ExclusionConstraints() []postgres.ExclusionConstraint {
+//		return []postgres.ExclusionConstraint{{Name: "no_double_booking", Elements: []postgres.ExclusionElement{
+//			{Column: "room_id", Operator: "="},
+//			{Column: "during", Operator: "&&"},
+//		}}}
+//	}
+type ExclusionConstrained interface {
+	ExclusionConstraints() []ExclusionConstraint
+}
+
+var _ schema.ConstraintInterface = ExclusionConstraint{}
+
+// GetName returns the name of the constraint
+func (constraint ExclusionConstraint) GetName() string {
+	return constraint.Name
+}
+
+// Build builds the constraint for `ALTER TABLE ? ADD`
+func (constraint ExclusionConstraint) Build() (sql string, vars []interface{}) {
+	sql = "CONSTRAINT ? EXCLUDE USING " + constraint.method() + " ("
+	vars = append(vars, clause.Column{Name: constraint.Name})
+	for idx, element := range constraint.Elements {
+		if idx > 0 {
+			sql += ", "
+		}
+		if element.Expression != "" {
+			sql += "(?)"
+			vars = append(vars, clause.Expr{SQL: element.Expression})
+		} else {
+			sql += "?"
+			vars = append(vars, clause.Column{Name: element.Column})
+		}
+		sql += " WITH " + element.Operator
+	}
+	sql += ")"
+	if constraint.Where != "" {
+		sql += " WHERE (?)"
+		vars = append(vars, clause.Expr{SQL: constraint.Where})
+	}
+	sql += constraint.options()
+	return
+}
+
+// definition returns the definition of constraint, comparable with the one of GetConstraints
+func (constraint ExclusionConstraint) definition() string {
+	elements := make([]string, 0, len(constraint.Elements))
+	for _, element := range constraint.Elements {
+		if element.Expression != "" {
+			elements = append(elements, "("+element.Expression+") WITH "+element.Operator)
+		} else {
+			elements = append(elements, element.Column+" WITH "+element.Operator)
+		}
+	}
+	definition := "EXCLUDE USING " + constraint.method() + " (" + strings.Join(elements, ", ") + ")"
+	if constraint.Where != "" {
+		definition += " WHERE (" + constraint.Where + ")"
+	}
+	return definition + constraint.options()
+}
+
+func (constraint ExclusionConstraint) method() string {
+	if constraint.Using == "" {
+		return "gist"
+	}
+	return strings.ToLower(constraint.Using)
+}
+
+func (constraint ExclusionConstraint) options() string {
+	if constraint.InitiallyDeferred {
+		return " DEFERRABLE INITIALLY DEFERRED"
+	}
+	if constraint.Deferrable {
+		return " DEFERRABLE"
+	}
+	return ""
+}
+
+// requiresBtreeGist returns whether constraint compares scalar columns in a gist index, which requires the `btree_gist` operator classes
+func (constraint ExclusionConstraint) requiresBtreeGist() bool {
+	if constraint.method() != "gist" {
+		return false
+	}
+	for _, element := range constraint.Elements {
+		if element.Operator == "=" || element.Operator == "<>" {
+			return true
+		}
+	}
+	return false
+}
+
+// exclusionConstraintsOf returns the exclusion constraints declared by the model of stmt
+func exclusionConstraintsOf(stmt *gorm.Statement) []ExclusionConstraint {
+	if stmt.Schema == nil {
+		return nil
+	}
+	if constrained, ok := reflect.New(stmt.Schema.ModelType).Interface().(ExclusionConstrained); ok {
+		return constrained.ExclusionConstraints()
+	}
+	return nil
+}
+
+// GuessConstraintInterfaceAndTable guesses the constraint name of stmt, the exclusion constraints of the model included
+func (m Migrator) GuessConstraintInterfaceAndTable(stmt *gorm.Statement, name string) (schema.ConstraintInterface, string) {
+	for _, constraint := range exclusionConstraintsOf(stmt) {
+		if constraint.Name == name {
+			return constraint, stmt.Table
+		}
+	}
+	return m.Migrator.GuessConstraintInterfaceAndTable(stmt, name)
+}
+
+// migrateExclusionConstraints creates the exclusion constraints declared by value, recreating the existing ones whose definition changed
+func (m Migrator) migrateExclusionConstraints(value interface{}, existing []ConstraintDefinition) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		for _, constraint := range exclusionConstraintsOf(stmt) {
+			definition := constraint.definition()
+			changed := func(current ConstraintDefinition) bool {
+				return normalizeConstraintDefinition(current.Definition) != normalizeConstraintDefinition(definition)
+			}
+			if err := m.migrateConstraint(value, constraint.Name, existing, changed); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+type exclusionBooking struct {
+	ID     uint64
+	RoomID uint64
+	During string `gorm:"type:tstzrange"`
+}
+
+func (exclusionBooking) ExclusionConstraints() []ExclusionConstraint {
+	return []ExclusionConstraint{{
+		Name:     "no_double_booking",
+		Elements: []ExclusionElement{{Column: "room_id", Operator: "="}, {Column: "during", Operator: "&&"}},
+		Where:    "cancelled_at IS NULL",
+	}}
+}
+
+func TestExclusionConstraint_Build(t *testing.T) {
+	tests := []struct {
+		name       string
+		constraint ExclusionConstraint
+		want       string
+		wantVars   int
+	}{
+		{
+			name:       "it should default to gist",
+			constraint: exclusionBooking{}.ExclusionConstraints()[0],
+			want:       "CONSTRAINT ? EXCLUDE USING gist (? WITH =, ? WITH &&) WHERE (?)",
+			wantVars:   4,
+		},
+		{
+			name: "it should build expressions and deferrable constraints",
+			constraint: ExclusionConstraint{
+				Name:              "no_overlap",
+				Using:             "GIST",
+				Elements:          []ExclusionElement{{Expression: "tstzrange(starts_at, ends_at)", Operator: "&&"}},
+				InitiallyDeferred: true,
+			},
+			want:     "CONSTRAINT ? EXCLUDE USING gist ((?) WITH &&) DEFERRABLE INITIALLY DEFERRED",
+			wantVars: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, vars := tt.constraint.Build()
+			if sql != tt.want || len(vars) != tt.wantVars {
+				t.Errorf("Build() = %v with %d vars, want %v with %d vars", sql, len(vars), tt.want, tt.wantVars)
+			}
+		})
+	}
+}
+
+func TestExclusionConstraint_definition(t *testing.T) {
+	constraint := exclusionBooking{}.ExclusionConstraints()[0]
+	catalog := "EXCLUDE USING gist (room_id WITH =, during WITH &&) WHERE ((cancelled_at IS NULL))"
+	if got, want := normalizeConstraintDefinition(constraint.definition()), normalizeConstraintDefinition(catalog); got != want {
+		t.Errorf("definition() normalized = %v, want %v", got, want)
+	}
+}
+
+func TestExclusionConstraint_requiresBtreeGist(t *testing.T) {
+	tests := []struct {
+		name       string
+		constraint ExclusionConstraint
+		want       bool
+	}{
+		{name: "it should require btree_gist for scalar equality", constraint: exclusionBooking{}.ExclusionConstraints()[0], want: true},
+		{name: "it should not require btree_gist for range operators", constraint: ExclusionConstraint{Elements: []ExclusionElement{{Column: "during", Operator: "&&"}}}},
+		{name: "it should not require btree_gist for other methods", constraint: ExclusionConstraint{Using: "hash", Elements: []ExclusionElement{{Column: "code", Operator: "="}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.constraint.requiresBtreeGist(); got != tt.want {
+				t.Errorf("requiresBtreeGist() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrator_GuessConstraintInterfaceAndTable_exclusion(t *testing.T) {
+	s, err := schema.Parse(&exclusionBooking{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("failed to parse schema, got error %v", err)
+	}
+	db := &gorm.DB{Config: &gorm.Config{}}
+	m := Migrator{migrator.Migrator{Config: migrator.Config{DB: db, Dialector: Dialector{Config: &Config{}}}}}
+
+	constraint, table := m.GuessConstraintInterfaceAndTable(&gorm.Statement{DB: db, Schema: s, Table: s.Table}, "no_double_booking")
+	if _, ok := constraint.(ExclusionConstraint); !ok || table != s.Table {
+		t.Errorf("GuessConstraintInterfaceAndTable() = %v, %v, want the exclusion constraint of %v", constraint, table, s.Table)
+	}
+}
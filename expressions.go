@@ -0,0 +1,225 @@
+package postgres
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// casts to quoted types, e.g. `::"char"`, to multi word types, e.g. `::character varying`, and to types with modifiers or of
+	// arrays, e.g. `::varchar(10)[]`
+	expressionCastPattern = regexp.MustCompile(
+		`::\s*(?:"[^"]+"|[a-z_][a-z0-9_.]*(?:\s+(?:varying|precision|with\s+time\s+zone|without\s+time\s+zone))*)(?:\s*\([0-9,\s]+\))?(?:\s*\[\])*`,
+	)
+	// unquoted and quoted identifiers, to lower only the unquoted ones
+	expressionCasePattern = regexp.MustCompile(`"[^"]*"|[^"]+`)
+	// quoted identifiers the database doesn't quote
+	expressionQuotedPattern = regexp.MustCompile(`"([a-z_][a-z0-9_$]*)"`)
+	expressionTokenPattern  = regexp.MustCompile(`'(?:[^']|'')*'?|"[^"]*"?|[a-z_$][a-z0-9_$.]*|[0-9][0-9.]*|[()\[\],]|[+\-*/<>=~!@#%^&|` + "`" + `?]+`)
+)
+
+// expressionPrecedences the precedences of the operators, from the loosest, other symbol operators come between LIKE and +
+var expressionPrecedences = map[string]int{
+	"or": 1, "and": 2, "not": 3,
+	"is": 4, "isnull": 4, "notnull": 4,
+	"=": 5, "<": 5, ">": 5, "<=": 5, ">=": 5, "<>": 5, "!=": 5,
+	"between": 6, "in": 6, "like": 6, "ilike": 6, "similar": 6,
+	"+": 8, "-": 8,
+	"*": 9, "/": 9, "%": 9,
+	"^": 10,
+}
+
+// expressionClauses the keywords followed by an expression, whose parentheses aren't the ones of a call
+var expressionClauses = map[string]bool{"select": true, "where": true, "on": true, "having": true, "when": true, "then": true, "else": true}
+
+const (
+	otherOperatorPrecedence = 7
+	unaryPrecedence         = 11
+	atomPrecedence          = 100
+)
+
+// normalizeExpression normalizes a SQL expression to compare it with its deparsed form in the catalog, type casts, case,
+// whitespaces and the parentheses the precedence of the operators makes redundant are dropped, string literals are kept
+//
+//	normalizeExpression("((a = 1) OR ((b)::text = 'x'::text))") == normalizeExpression("a = 1 OR b = 'x'")
+func normalizeExpression(expr string) string {
+	expr = mapOutsideLiterals(expr, func(s string) string {
+		s = expressionCasePattern.ReplaceAllStringFunc(s, func(part string) string {
+			if strings.HasPrefix(part, `"`) {
+				return part
+			}
+			return strings.ToLower(part)
+		})
+		s = expressionCastPattern.ReplaceAllString(s, "")
+		return expressionQuotedPattern.ReplaceAllString(s, "$1")
+	})
+	return strings.Join(removeRedundantParens(tokenizeExpression(expr)), " ")
+}
+
+// mapOutsideLiterals replaces the parts of expr outside of its string literals with fn
+func mapOutsideLiterals(expr string, fn func(string) string) string {
+	var builder strings.Builder
+	for {
+		start := strings.IndexByte(expr, '\'')
+		if start < 0 {
+			builder.WriteString(fn(expr))
+			return builder.String()
+		}
+		end := start + 1
+		for ; end < len(expr); end++ {
+			if expr[end] == '\'' {
+				if end+1 < len(expr) && expr[end+1] == '\'' {
+					end++
+					continue
+				}
+				break
+			}
+		}
+		if end < len(expr) {
+			end++
+		}
+		builder.WriteString(fn(expr[:start]))
+		builder.WriteString(expr[start:end])
+		expr = expr[end:]
+	}
+}
+
+// tokenizeExpression splits expr into its literals, identifiers, numbers, parentheses and operators
+func tokenizeExpression(expr string) []string {
+	return expressionTokenPattern.FindAllString(expr, -1)
+}
+
+// removeRedundantParens removes the pairs of parentheses whose content binds tighter than the operators around them,
+// the parentheses of function calls, lists, rows and subqueries are kept
+func removeRedundantParens(tokens []string) []string {
+	for {
+		var (
+			opens   []int
+			removed bool
+		)
+		for idx := 0; idx < len(tokens) && !removed; idx++ {
+			switch tokens[idx] {
+			case "(":
+				opens = append(opens, idx)
+			case ")":
+				if len(opens) == 0 {
+					continue
+				}
+				open := opens[len(opens)-1]
+				opens = opens[:len(opens)-1]
+				if redundantParens(tokens, open, idx) {
+					tokens = append(tokens[:open], append(tokens[open+1:idx], tokens[idx+1:]...)...)
+					removed = true
+				}
+			}
+		}
+		if !removed {
+			return tokens
+		}
+	}
+}
+
+// redundantParens returns whether the parentheses of tokens at open and close can be dropped
+func redundantParens(tokens []string, open, close int) bool {
+	if open > 0 && isExpressionWord(tokens[open-1]) && !expressionClauses[tokens[open-1]] {
+		// function calls, `IN (...)`, `ANY (...)`, `CHECK (...)`
+		if _, operator := expressionPrecedences[tokens[open-1]]; !operator || tokens[open-1] == "in" {
+			return false
+		}
+	}
+	content := tokens[open+1 : close]
+	if len(content) == 0 || content[0] == "select" || content[0] == "with" || content[0] == "values" {
+		return false
+	}
+
+	inner, depth := atomPrecedence, 0
+	for idx, token := range content {
+		switch token {
+		case "(", "[":
+			depth++
+			continue
+		case ")", "]":
+			depth--
+			continue
+		}
+		if depth > 0 {
+			continue
+		}
+		if token == "," {
+			return false
+		}
+		if precedence := operatorPrecedence(content, idx); precedence > 0 && precedence < inner {
+			inner = precedence
+		}
+	}
+
+	var left, right int
+	if open > 0 {
+		left = operatorPrecedence(tokens, open-1)
+	}
+	if close+1 < len(tokens) {
+		right = operatorPrecedence(tokens, close+1)
+	}
+	return inner > left && inner >= right
+}
+
+// operatorPrecedence returns the precedence of the operator tokens[idx], zero when it isn't an operator
+func operatorPrecedence(tokens []string, idx int) int {
+	token := tokens[idx]
+	if isExpressionWord(token) {
+		precedence := expressionPrecedences[token]
+		switch {
+		case token == "not" && idx > 0 && tokens[idx-1] == "is":
+			// IS NOT
+			return 0
+		case token == "not" && idx+1 < len(tokens) && expressionPrecedences[tokens[idx+1]] == expressionPrecedences["like"]:
+			// NOT LIKE, NOT IN, NOT BETWEEN
+			return 0
+		case token == "and" && betweenAnd(tokens, idx):
+			return expressionPrecedences["between"]
+		}
+		return precedence
+	}
+	if !strings.ContainsAny(token[:1], "+-*/<>=~!@#%^&|`?") {
+		return 0
+	}
+	if token == "-" || token == "+" {
+		if idx == 0 || tokens[idx-1] == "(" || tokens[idx-1] == "[" || tokens[idx-1] == "," || operatorPrecedence(tokens, idx-1) > 0 {
+			return unaryPrecedence
+		}
+	}
+	if precedence, ok := expressionPrecedences[token]; ok {
+		return precedence
+	}
+	return otherOperatorPrecedence
+}
+
+// betweenAnd returns whether the AND tokens[idx] ends a `BETWEEN x AND y`
+func betweenAnd(tokens []string, idx int) bool {
+	depth := 0
+	for idx--; idx >= 0; idx-- {
+		switch tokens[idx] {
+		case ")", "]":
+			depth++
+		case "(", "[":
+			if depth == 0 {
+				return false
+			}
+			depth--
+		case "between":
+			if depth == 0 {
+				return true
+			}
+		case "and", "or":
+			if depth == 0 {
+				return false
+			}
+		}
+	}
+	return false
+}
+
+// isExpressionWord returns whether token is a keyword or an identifier, quoted or not
+func isExpressionWord(token string) bool {
+	return token != "" && (token[0] == '"' || token[0] == '_' || token[0] == '$' || (token[0] >= 'a' && token[0] <= 'z'))
+}
@@ -0,0 +1,123 @@
+package postgres
+
+import "testing"
+
+func Test_normalizeExpression(t *testing.T) {
+	tests := []struct {
+		name     string
+		declared string
+		deparsed string
+		changed  bool
+	}{
+		{
+			name:     "it should ignore casts and parentheses",
+			declared: "tenant_id = current_setting('app.tenant_id')::bigint",
+			deparsed: "(tenant_id = (current_setting('app.tenant_id'::text))::bigint)",
+		},
+		{
+			name:     "it should ignore whitespaces and case",
+			declared: "owner   =  CURRENT_USER",
+			deparsed: "(owner = CURRENT_USER)",
+		},
+		{
+			name:     "it should ignore multi word casts",
+			declared: "status <> 'archived'",
+			deparsed: "((status)::text <> 'archived'::character varying::text)",
+		},
+		{
+			name:     "it should ignore casts to quoted types",
+			declared: "kind = 'a'",
+			deparsed: `(kind = 'a'::"char")`,
+		},
+		{
+			name:     "it should ignore casts to arrays",
+			declared: "role = ANY (ARRAY['admin', 'owner'])",
+			deparsed: "((role)::text = ANY ((ARRAY['admin'::character varying, 'owner'::character varying])::text[]))",
+		},
+		{
+			name:     "it should ignore casts to types with modifiers",
+			declared: "code = 'x'",
+			deparsed: "((code)::character varying(10) = 'x'::bpchar(1))",
+		},
+		{
+			name:     "it should ignore the parentheses added around each operation",
+			declared: "a = 1 OR b = 2 AND c = 3",
+			deparsed: "((a = 1) OR ((b = 2) AND (c = 3)))",
+		},
+		{
+			name:     "it should keep the parentheses changing the precedence",
+			declared: "(a = 1 OR b = 2) AND c = 3",
+			deparsed: "((a = 1) OR ((b = 2) AND (c = 3)))",
+			changed:  true,
+		},
+		{
+			name:     "it should keep the parentheses of right operands of the same precedence",
+			declared: "price - discount - tax",
+			deparsed: "(price - (discount - tax))",
+			changed:  true,
+		},
+		{
+			name:     "it should ignore the parentheses of left operands of the same precedence",
+			declared: "price - discount - tax",
+			deparsed: "((price - discount) - tax)",
+		},
+		{
+			name:     "it should ignore the parentheses of negated comparisons",
+			declared: "NOT archived AND owner <> 'root'",
+			deparsed: "((NOT archived) AND (owner <> 'root'::text))",
+		},
+		{
+			name:     "it should keep the parentheses of negated operations",
+			declared: "NOT (archived OR deleted)",
+			deparsed: "((NOT archived) OR deleted)",
+			changed:  true,
+		},
+		{
+			name:     "it should ignore the parentheses of generated expressions",
+			declared: "price * quantity",
+			deparsed: "(price * (quantity)::numeric)",
+		},
+		{
+			name:     "it should keep the whitespaces of string literals",
+			declared: "name = 'a b'",
+			deparsed: "(name = 'ab'::text)",
+			changed:  true,
+		},
+		{
+			name:     "it should keep the case of string literals",
+			declared: "status = 'Active'",
+			deparsed: "(status = 'active'::text)",
+			changed:  true,
+		},
+		{
+			name:     "it should keep the parentheses of string literals",
+			declared: "note = '(a)'",
+			deparsed: "(note = 'a'::text)",
+			changed:  true,
+		},
+		{
+			name:     "it should ignore the quotes of lower case identifiers",
+			declared: `"owner" = CURRENT_USER`,
+			deparsed: "(owner = CURRENT_USER)",
+		},
+		{
+			name:     "it should keep the case of quoted identifiers",
+			declared: `"Owner" = CURRENT_USER`,
+			deparsed: "(owner = CURRENT_USER)",
+			changed:  true,
+		},
+		{
+			name:     "it should keep the parentheses of lists and subqueries",
+			declared: "status IN ('a', 'b') AND EXISTS (SELECT 1 FROM members WHERE members.id = owner_id)",
+			deparsed: "((status IN ('a', 'b')) AND (EXISTS ( SELECT 1 FROM members WHERE (members.id = owner_id))))",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			declared, deparsed := normalizeExpression(tt.declared), normalizeExpression(tt.deparsed)
+			if changed := declared != deparsed; changed != tt.changed {
+				t.Errorf("normalizeExpression() = %v and %v, want changed %v", declared, deparsed, tt.changed)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package postgres
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Extension an extension installed in the database
+type Extension struct {
+	Name    string
+	Schema  string
+	Version string
+}
+
+// ExtensionOption configures CreateExtension
+type ExtensionOption struct {
+	// Schema the extension objects are created in, defaults to the current schema
+	Schema string
+	// Version defaults to the default version of the extension
+	Version string
+	// Cascade creates the extensions it depends on too
+	Cascade bool
+}
+
+var (
+	// typeExtensions the extensions providing column types
+	typeExtensions = map[string]string{
+		"citext":    "citext",
+		"hstore":    "hstore",
+		"ltree":     "ltree",
+		"lquery":    "ltree",
+		"cube":      "cube",
+		"isbn":      "isn",
+		"issn":      "isn",
+		"ean13":     "isn",
+		"geometry":  "postgis",
+		"geography": "postgis",
+		"raster":    "postgis_raster",
+		"vector":    "vector",
+	}
+	// indexExtensions the extensions providing index methods and operator classes
+	indexExtensions = map[string]string{
+		"bloom":           "bloom",
+		"gin_trgm_ops":    "pg_trgm",
+		"gist_trgm_ops":   "pg_trgm",
+		"gist_ltree_ops":  "ltree",
+		"gist_hstore_ops": "hstore",
+		"gin_hstore_ops":  "hstore",
+		"vector_l2_ops":   "vector",
+		"vector_ip_ops":   "vector",
+		"hnsw":            "vector",
+		"ivfflat":         "vector",
+	}
+	// functionExtensions the extensions providing functions used in default values
+	functionExtensions = map[string]string{
+		"uuid_generate_v1":     "uuid-ossp",
+		"uuid_generate_v4":     "uuid-ossp",
+		"gen_random_bytes":     "pgcrypto",
+		"crypt":                "pgcrypto",
+		"digest":               "pgcrypto",
+		"earth_distance":       "earthdistance",
+		"unaccent":             "unaccent",
+		"st_makepoint":         "postgis",
+		"st_setsrid":           "postgis",
+		"st_geomfromtext":      "postgis",
+		"st_geographyfromtext": "postgis",
+	}
+	identifierWordPattern = regexp.MustCompile(`[a-zA-Z_][a-zA-Z0-9_]*`)
+)
+
+// HasExtension returns whether the extension name is installed
+func (m Migrator) HasExtension(name string) bool {
+	var count int64
+	m.queryRaw("SELECT count(*) FROM pg_catalog.pg_extension WHERE extname = ?", name).Scan(&count)
+	return count > 0
+}
+
+// CreateExtension installs the extension name if it isn't installed yet
+//
+//	db.Migrator().(postgres.Migrator).CreateExtension("postgis", postgres.ExtensionOption{Schema: "extensions", Cascade: true})
+func (m Migrator) CreateExtension(name string, opts ...ExtensionOption) error {
+	createSQL := "CREATE EXTENSION IF NOT EXISTS ?"
+	values := []interface{}{clause.Table{Name: name}}
+	for _, opt := range opts {
+		if opt.Schema != "" {
+			createSQL += " SCHEMA ?"
+			values = append(values, clause.Table{Name: opt.Schema})
+		}
+		if opt.Version != "" {
+			// a string literal, quoted identifiers would be split on the dots of the version, and DDL doesn't accept bind parameters
+			createSQL += " VERSION '" + strings.ReplaceAll(opt.Version, "'", "''") + "'"
+		}
+		if opt.Cascade {
+			createSQL += " CASCADE"
+		}
+	}
+	return m.DB.Exec(createSQL, values...).Error
+}
+
+// DropExtension drops the extension name if it is installed, cascade drops the objects depending on it too
+func (m Migrator) DropExtension(name string, cascade bool) error {
+	dropSQL := "DROP EXTENSION IF EXISTS ?"
+	if cascade {
+		dropSQL += " CASCADE"
+	}
+	return m.DB.Exec(dropSQL, clause.Table{Name: name}).Error
+}
+
+// GetExtensions returns the extensions installed in the database
+func (m Migrator) GetExtensions() (extensions []Extension, err error) {
+	rows, err := m.queryRaw(
+		"SELECT e.extname, n.nspname, e.extversion FROM pg_catalog.pg_extension e JOIN pg_catalog.pg_namespace n ON n.oid = e.extnamespace ORDER BY e.extname",
+	).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var extension Extension
+		if err := rows.Scan(&extension.Name, &extension.Schema, &extension.Version); err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, extension)
+	}
+	return extensions, rows.Err()
+}
+
+// ensureExtension installs the extension name unless it is installed already
+func (m Migrator) ensureExtension(name string) error {
+	if m.HasExtension(name) {
+		return nil
+	}
+	return m.CreateExtension(name)
+}
+
+// createRequiredExtensions installs the extensions required by the column types, indexes and default values of value
+func (m Migrator) createRequiredExtensions(value interface{}) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		for _, name := range m.requiredExtensions(stmt) {
+			if err := m.ensureExtension(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// requiredExtensions returns the extensions required by the model of stmt, sorted by name
+func (m Migrator) requiredExtensions(stmt *gorm.Statement) []string {
+	if stmt.Schema == nil {
+		return nil
+	}
+
+	required := map[string]bool{}
+	for _, dbName := range stmt.Schema.DBNames {
+		field := stmt.Schema.FieldsByDBName[dbName]
+		if field.IgnoreMigration {
+			continue
+		}
+		if name, ok := typeExtensions[baseTypeName(m.DataTypeOf(field))]; ok {
+			required[name] = true
+		}
+		if field.HasDefaultValue && field.DefaultValueInterface == nil {
+			for _, word := range identifierWordPattern.FindAllString(field.DefaultValue, -1) {
+				if name, ok := functionExtensions[strings.ToLower(word)]; ok {
+					required[name] = true
+				}
+			}
+		}
+	}
+
+	for _, idx := range stmt.Schema.ParseIndexes() {
+		words := []string{idx.Type}
+		words = append(words, identifierWordPattern.FindAllString(idx.Option, -1)...)
+		for _, opt := range idx.Fields {
+			words = append(words, identifierWordPattern.FindAllString(opt.Expression, -1)...)
+			words = append(words, identifierWordPattern.FindAllString(indexFieldSettings(stmt, opt.Field, idx.Name)["OPCLASS"], -1)...)
+		}
+		for _, word := range words {
+			if name, ok := indexExtensions[strings.ToLower(word)]; ok {
+				required[name] = true
+			}
+		}
+	}
+
+	for _, constraint := range exclusionConstraintsOf(stmt) {
+		if constraint.requiresBtreeGist() {
+			required["btree_gist"] = true
+		}
+	}
+
+	names := make([]string, 0, len(required))
+	for name := range required {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// baseTypeName returns the type name of a column type, without its modifiers and array bounds, e.g. `geometry` of `geometry(Point, 4326)[]`
+func baseTypeName(dataType string) string {
+	dataType = strings.ToLower(strings.TrimSpace(dataType))
+	if idx := strings.IndexAny(dataType, "([ "); idx >= 0 {
+		dataType = dataType[:idx]
+	}
+	if idx := strings.LastIndex(dataType, "."); idx >= 0 {
+		dataType = dataType[idx+1:]
+	}
+	return strings.Trim(dataType, `"`)
+}
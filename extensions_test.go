@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+type extensionPlace struct {
+	ID       string `gorm:"type:uuid;default:uuid_generate_v4()"`
+	Email    string `gorm:"type:citext"`
+	Name     string `gorm:"index:,type:gin,expression:name gin_trgm_ops"`
+	Location string `gorm:"type:geometry(Point, 4326)"`
+}
+
+func (extensionPlace) ExclusionConstraints() []ExclusionConstraint {
+	return []ExclusionConstraint{{Name: "no_duplicate_place", Elements: []ExclusionElement{{Column: "name", Operator: "="}}}}
+}
+
+type extensionFreePlace struct {
+	ID   uint64
+	Name string `gorm:"index"`
+}
+
+func TestMigrator_requiredExtensions(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  []string
+	}{
+		{
+			name:  "it should require the extensions of types, indexes, defaults and exclusion constraints",
+			value: &extensionPlace{},
+			want:  []string{"btree_gist", "citext", "pg_trgm", "postgis", "uuid-ossp"},
+		},
+		{name: "it should require no extension for core types", value: &extensionFreePlace{}, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := schema.Parse(tt.value, &sync.Map{}, schema.NamingStrategy{})
+			if err != nil {
+				t.Fatalf("failed to parse schema, got error %v", err)
+			}
+			dialector := Dialector{Config: &Config{}}
+			db := &gorm.DB{Config: &gorm.Config{Dialector: dialector}}
+			m := Migrator{migrator.Migrator{Config: migrator.Config{DB: db, Dialector: dialector}}}
+
+			if got := m.requiredExtensions(&gorm.Statement{DB: db, Schema: s, Table: s.Table}); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("requiredExtensions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_baseTypeName(t *testing.T) {
+	tests := []struct {
+		name     string
+		dataType string
+		want     string
+	}{
+		{name: "it should keep plain types", dataType: "citext", want: "citext"},
+		{name: "it should drop modifiers", dataType: "geometry(Point, 4326)", want: "geometry"},
+		{name: "it should drop array bounds", dataType: "CITEXT[]", want: "citext"},
+		{name: "it should drop the schema", dataType: `"public"."hstore"`, want: "hstore"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := baseTypeName(tt.dataType); got != tt.want {
+				t.Errorf("baseTypeName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrator_CreateExtension(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []ExtensionOption
+		want string
+	}{
+		{name: "it should create the extension", want: `CREATE EXTENSION IF NOT EXISTS "postgis"`},
+		{
+			name: "it should quote a dotted version as a string",
+			opts: []ExtensionOption{{Schema: "extensions", Version: "3.4.2", Cascade: true}},
+			want: `CREATE EXTENSION IF NOT EXISTS "postgis" SCHEMA "extensions" VERSION '3.4.2' CASCADE`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &planRecorder{Interface: logger.Discard, plan: &migrationPlan{}}
+			m := dryRunDB(t).Session(&gorm.Session{Logger: recorder}).Migrator().(Migrator)
+			if err := m.CreateExtension("postgis", tt.opts...); err != nil {
+				t.Fatalf("CreateExtension() error = %v", err)
+			}
+			if got := recorder.plan.sql; !reflect.DeepEqual(got, []string{tt.want}) {
+				t.Errorf("CreateExtension() executed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
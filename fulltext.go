@@ -0,0 +1,183 @@
+package postgres
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const defaultTextSearchConfig = "simple"
+
+// TSVector a `tsvector` column, declared with the `tsvector` tag it is generated from the weighted columns of the model
+// in the `tsconfig` configuration, `simple` by default, and indexed with GIN
+//
+//	Title  string
+//	Body   string
+//	Search postgres.TSVector `gorm:"tsvector:title:A,body:B;tsconfig:english"`
+type TSVector string
+
+// GormDataType gorm common data type
+func (TSVector) GormDataType() string {
+	return "tsvector"
+}
+
+// Scan implements the sql.Scanner interface
+func (v *TSVector) Scan(value interface{}) error {
+	switch value := value.(type) {
+	case string:
+		*v = TSVector(value)
+	case []byte:
+		*v = TSVector(value)
+	case nil:
+		*v = ""
+	default:
+		return fmt.Errorf("failed to scan tsvector value: %v", value)
+	}
+	return nil
+}
+
+// Value implements the driver.Valuer interface
+func (v TSVector) Value() (driver.Value, error) {
+	return string(v), nil
+}
+
+// TSQuery a text search query parsed by Func, `websearch_to_tsquery` by default, in the configuration Config
+type TSQuery struct {
+	Query  string
+	Config string
+	// Func e.g. plainto_tsquery, phraseto_tsquery or to_tsquery
+	Func string
+}
+
+// Build builds the query
+func (query TSQuery) Build(builder clause.Builder) {
+	fn := query.Func
+	if fn == "" {
+		fn = "websearch_to_tsquery"
+	}
+	builder.WriteString(fn + "(")
+	if query.Config != "" {
+		builder.AddVar(builder, query.Config)
+		builder.WriteString(", ")
+	}
+	builder.AddVar(builder, query.Query)
+	builder.WriteByte(')')
+}
+
+// TSMatch matches the tsvector column Column with Query
+//
+//	db.Where(postgres.TSMatch{Column: "search", Query: postgres.TSQuery{Query: "gorm -mysql", Config: "english"}}).Find(&posts)
+type TSMatch struct {
+	Column string
+	Query  TSQuery
+}
+
+// Build builds the `@@` condition
+func (match TSMatch) Build(builder clause.Builder) {
+	builder.WriteQuoted(clause.Column{Name: match.Column})
+	builder.WriteString(" @@ ")
+	match.Query.Build(builder)
+}
+
+// TSRank ranks the tsvector column Column against Query with `ts_rank`
+//
+//	rank := postgres.TSRank{Column: "search", Query: query}
+//	db.Where(postgres.TSMatch{Column: "search", Query: query}).Clauses(rank.OrderBy()).Find(&posts)
+type TSRank struct {
+	Column string
+	Query  TSQuery
+}
+
+// Build builds the `ts_rank` call
+func (rank TSRank) Build(builder clause.Builder) {
+	builder.WriteString("ts_rank(")
+	builder.WriteQuoted(clause.Column{Name: rank.Column})
+	builder.WriteString(", ")
+	rank.Query.Build(builder)
+	builder.WriteByte(')')
+}
+
+// OrderBy orders by rank, the best matches first
+func (rank TSRank) OrderBy() clause.OrderBy {
+	return clause.OrderBy{Expression: clause.Expr{SQL: "? DESC", Vars: []interface{}{rank}}}
+}
+
+// TSHeadline highlights the matches of Query in the text column Column with `ts_headline`, Options are its options,
+// e.g. `StartSel=<b>, StopSel=</b>`
+//
+//	db.Model(&Post{}).Select("id, ? AS snippet", postgres.TSHeadline{Column: "body", Query: query}).Scan(&snippets)
+type TSHeadline struct {
+	Column  string
+	Query   TSQuery
+	Options string
+}
+
+// Build builds the `ts_headline` call
+func (headline TSHeadline) Build(builder clause.Builder) {
+	builder.WriteString("ts_headline(")
+	if headline.Query.Config != "" {
+		builder.AddVar(builder, headline.Query.Config)
+		builder.WriteString(", ")
+	}
+	builder.WriteQuoted(clause.Column{Name: headline.Column})
+	builder.WriteString(", ")
+	headline.Query.Build(builder)
+	if headline.Options != "" {
+		builder.WriteString(", ")
+		builder.AddVar(builder, headline.Options)
+	}
+	builder.WriteByte(')')
+}
+
+// tsvectorExpression returns the generation expression of a tsvector column declared with the `tsvector` tag
+func tsvectorExpression(field *schema.Field) (string, bool) {
+	columns := field.TagSettings["TSVECTOR"]
+	if columns == "" {
+		return "", false
+	}
+	config := field.TagSettings["TSCONFIG"]
+	if config == "" {
+		config = defaultTextSearchConfig
+	}
+	config = "'" + strings.ReplaceAll(config, "'", "''") + "'"
+
+	var parts []string
+	for _, column := range strings.Split(columns, ",") {
+		name, weight, weighted := strings.Cut(strings.TrimSpace(column), ":")
+		part := "to_tsvector(" + config + ", coalesce(" + name + ", ''))"
+		if weighted {
+			part = "setweight(" + part + ", '" + strings.ToUpper(strings.TrimSpace(weight)) + "')"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " || "), true
+}
+
+// migrateSearchIndexes creates the GIN indexes of the tsvector columns declared with the `tsvector` tag, like the indexes declared
+// with the `index` tag, e.g. concurrently in online mode and with the options of IndexOptioned
+func (m Migrator) migrateSearchIndexes(value interface{}) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if stmt.Schema == nil {
+			return nil
+		}
+		for _, dbName := range stmt.Schema.DBNames {
+			field := stmt.Schema.FieldsByDBName[dbName]
+			if _, ok := tsvectorExpression(field); !ok || field.IgnoreMigration {
+				continue
+			}
+
+			name := m.DB.NamingStrategy.IndexName(stmt.Table, field.DBName)
+			if m.HasIndex(value, name) {
+				continue
+			}
+			if err := m.createIndex(value, stmt, &schema.Index{Name: name, Type: "gin", Fields: []schema.IndexOption{{Field: field}}}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
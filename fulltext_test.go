@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm/schema"
+)
+
+type searchPost struct {
+	ID     uint64
+	Title  string
+	Body   string
+	Search TSVector `gorm:"tsvector:title:A,body:b;tsconfig:english"`
+	Plain  TSVector `gorm:"tsvector:title"`
+}
+
+func Test_tsvectorExpression(t *testing.T) {
+	s, err := schema.Parse(&searchPost{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("failed to parse schema, got error %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		field   string
+		want    string
+		catalog string
+	}{
+		{
+			name:    "it should weight the columns in the configuration",
+			field:   "Search",
+			want:    "setweight(to_tsvector('english', coalesce(title, '')), 'A') || setweight(to_tsvector('english', coalesce(body, '')), 'B')",
+			catalog: `(setweight(to_tsvector('english'::regconfig, COALESCE(title, ''::text)), 'A'::"char") || setweight(to_tsvector('english'::regconfig, COALESCE(body, ''::text)), 'B'::"char"))`,
+		},
+		{
+			name:    "it should default to the simple configuration",
+			field:   "Plain",
+			want:    "to_tsvector('simple', coalesce(title, ''))",
+			catalog: `to_tsvector('simple'::regconfig, COALESCE(title, ''::text))`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expression, virtual, ok := generatedColumnOf(s.LookUpField(tt.field))
+			if !ok || virtual || expression != tt.want {
+				t.Fatalf("generatedColumnOf() = %v, %v, %v, want the stored column %v", expression, virtual, ok, tt.want)
+			}
+			if normalizeExpression(expression) != normalizeExpression(tt.catalog) {
+				t.Errorf("expected the expression to match its deparsed form %v", tt.catalog)
+			}
+		})
+	}
+}
+
+func TestTextSearchClauses(t *testing.T) {
+	tx := dryRunDB(t)
+	query := TSQuery{Query: "gorm -mysql", Config: "english"}
+
+	stmt := tx.Where(TSMatch{Column: "search", Query: query}).Clauses(TSRank{Column: "search", Query: query}.OrderBy()).Find(&[]searchPost{}).Statement
+	if want := `SELECT * FROM "search_posts" WHERE "search" @@ websearch_to_tsquery($1, $2) ORDER BY ts_rank("search", websearch_to_tsquery($3, $4)) DESC`; stmt.SQL.String() != want {
+		t.Errorf("expected %v, got %v", want, stmt.SQL.String())
+	}
+
+	stmt = tx.Model(&searchPost{}).Select("id, ? AS snippet", TSHeadline{Column: "body", Query: TSQuery{Query: "gorm", Func: "plainto_tsquery"}, Options: "MaxWords=10"}).Find(&[]map[string]interface{}{}).Statement
+	if want := `SELECT id, ts_headline("body", plainto_tsquery($1), $2) AS snippet FROM "search_posts"`; stmt.SQL.String() != want {
+		t.Errorf("expected %v, got %v", want, stmt.SQL.String())
+	}
+}
+
+func TestMigrator_migrateSearchIndexes(t *testing.T) {
+	tests := []struct {
+		name   string
+		online bool
+		want   []string
+	}{
+		{
+			name: "it should create the missing GIN indexes",
+			want: []string{
+				`CREATE INDEX IF NOT EXISTS "idx_search_posts_search" ON "search_posts" USING gin("search")`,
+				`CREATE INDEX IF NOT EXISTS "idx_search_posts_plain" ON "search_posts" USING gin("plain")`,
+			},
+		},
+		{
+			name:   "it should create the GIN indexes concurrently online",
+			online: true,
+			want: []string{
+				`CREATE INDEX CONCURRENTLY IF NOT EXISTS "idx_search_posts_search" ON "search_posts" USING gin("search")`,
+				`CREATE INDEX CONCURRENTLY IF NOT EXISTS "idx_search_posts_plain" ON "search_posts" USING gin("plain")`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, connector := stubDB(t, func(query string) ([]string, [][]driver.Value, error) {
+				if strings.Contains(query, "FROM pg_indexes") {
+					return []string{"count"}, [][]driver.Value{{int64(0)}}, nil
+				}
+				return nil, nil, nil
+			})
+			if tt.online {
+				db = WithOnlineMigration(db, OnlineMigration{})
+			}
+
+			if err := db.Migrator().(Migrator).migrateSearchIndexes(&searchPost{}); err != nil {
+				t.Fatalf("migrateSearchIndexes() error = %v", err)
+			}
+			var created []string
+			for _, statement := range connector.statements() {
+				if strings.HasPrefix(statement, "CREATE INDEX") {
+					created = append(created, statement)
+				}
+			}
+			if !reflect.DeepEqual(created, tt.want) {
+				t.Errorf("migrateSearchIndexes() executed %q, want %q", created, tt.want)
+			}
+		})
+	}
+}
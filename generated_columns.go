@@ -0,0 +1,195 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+const generatedColumnRecreationKey = "postgres:generated_column_recreation"
+
+// setExpressionVersion the server version supporting `ALTER COLUMN ... SET EXPRESSION`
+const setExpressionVersion = 170000
+
+// migratorColumnType is embedded by ColumnType, the embedded field can't be named like the ColumnType method
+type migratorColumnType = migrator.ColumnType
+
+// ColumnType the type of a column, with its generation expression when it is a generated column
+type ColumnType struct {
+	*migratorColumnType
+	GeneratedValue sql.NullString
+	VirtualValue   bool
+}
+
+// Generated returns the expression of a generated column
+func (ct ColumnType) Generated() (expression string, ok bool) {
+	return ct.GeneratedValue.String, ct.GeneratedValue.Valid
+}
+
+// Virtual returns whether a generated column is computed when it is read instead of stored
+func (ct ColumnType) Virtual() bool {
+	return ct.VirtualValue
+}
+
+// generatedColumnOf returns the expression of a generated column declared with the `generated` or the `tsvector` tag,
+// stored unless tagged `virtual`
+//
+//	Total decimal `gorm:"generated:price * quantity"`
+//	Label string  `gorm:"generated:upper(name);virtual"`
+func generatedColumnOf(field *schema.Field) (expression string, virtual, ok bool) {
+	expression, ok = field.TagSettings["GENERATED"]
+	_, virtual = field.TagSettings["VIRTUAL"]
+	if !ok || expression == "" {
+		expression, ok = tsvectorExpression(field)
+	}
+	return expression, virtual, ok && expression != ""
+}
+
+// generatedFieldsOf returns the fields of s declared as generated columns
+func generatedFieldsOf(s *schema.Schema) (fields []*schema.Field) {
+	for _, dbName := range s.DBNames {
+		if _, _, ok := generatedColumnOf(s.FieldsByDBName[dbName]); ok {
+			fields = append(fields, s.FieldsByDBName[dbName])
+		}
+	}
+	return
+}
+
+// FullDataTypeOf returns field's column definition, generated columns are defined by their expression instead of a default value
+func (m Migrator) FullDataTypeOf(field *schema.Field) clause.Expr {
+	expression, virtual, ok := generatedColumnOf(field)
+	if !ok {
+		return m.Migrator.FullDataTypeOf(field)
+	}
+
+	withoutDefault := *field
+	withoutDefault.HasDefaultValue = false
+	expr := m.Migrator.FullDataTypeOf(&withoutDefault)
+	expr.SQL += " GENERATED ALWAYS AS (" + expression + ")"
+	if virtual {
+		expr.SQL += " VIRTUAL"
+	} else {
+		expr.SQL += " STORED"
+	}
+	return expr
+}
+
+// WithGeneratedColumnRecreation returns a session whose AutoMigrate drops and adds again the generated columns that can't be
+// altered, the columns switching between stored and virtual, and before Postgres 17 the columns whose expression changed,
+// the indexes, constraints and views depending on them are dropped with them
+//
+//	postgres.WithGeneratedColumnRecreation(db).AutoMigrate(&Line{})
+func WithGeneratedColumnRecreation(db *gorm.DB) *gorm.DB {
+	return db.Set(generatedColumnRecreationKey, true)
+}
+
+func generatedColumnRecreationOf(db *gorm.DB) bool {
+	v, ok := db.Get(generatedColumnRecreationKey)
+	return ok && v == true
+}
+
+// serverVersion returns the version of the server as a number, e.g. 170002 for 17.2
+func (m Migrator) serverVersion() (version int, err error) {
+	err = m.queryRaw("SELECT current_setting('server_version_num')::int").Scan(&version).Error
+	return
+}
+
+// migrateGeneratedColumn migrates the generation expression of field's column with `SET EXPRESSION` of Postgres 17, a stored
+// column can't become virtual and the other way round, such columns are only added again WithGeneratedColumnRecreation
+func (m Migrator) migrateGeneratedColumn(value interface{}, field *schema.Field, columnType gorm.ColumnType) error {
+	var (
+		currentExpression             string
+		generated, wasVirtual         bool
+		expression, virtual, declared = generatedColumnOf(field)
+	)
+	if current, ok := columnType.(*ColumnType); ok {
+		currentExpression, generated = current.Generated()
+		wasVirtual = current.Virtual()
+	}
+
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		switch {
+		case declared && !generated:
+			return fmt.Errorf("failed to migrate column %s to a generated column, its values would be lost, drop it first", field.DBName)
+		case !declared && generated:
+			// keep the computed values as regular values
+			return m.DB.Exec("ALTER TABLE ? ALTER COLUMN ? DROP EXPRESSION", m.CurrentTable(stmt), clause.Column{Name: field.DBName}).Error
+		case !declared:
+			return nil
+		case virtual != wasVirtual:
+			return m.recreateGeneratedColumn(value, field, "it switches between stored and virtual")
+		case normalizeExpression(currentExpression) != normalizeExpression(expression):
+			version, err := m.serverVersion()
+			if err != nil {
+				return err
+			}
+			if version < setExpressionVersion {
+				return m.recreateGeneratedColumn(value, field, "changing its expression requires Postgres 17")
+			}
+			return m.DB.Exec(
+				"ALTER TABLE ? ALTER COLUMN ? SET EXPRESSION AS (?)", m.CurrentTable(stmt), clause.Column{Name: field.DBName}, clause.Expr{SQL: expression},
+			).Error
+		}
+		return nil
+	})
+}
+
+// recreateGeneratedColumn drops and adds field's generated column again WithGeneratedColumnRecreation, as it can't be altered
+// for reason
+func (m Migrator) recreateGeneratedColumn(value interface{}, field *schema.Field, reason string) error {
+	if !generatedColumnRecreationOf(m.DB) {
+		return fmt.Errorf("failed to migrate generated column %s, %s, recreate it WithGeneratedColumnRecreation", field.DBName, reason)
+	}
+	if err := m.DropColumn(value, field.DBName); err != nil {
+		return err
+	}
+	return m.AddColumn(value, field.DBName)
+}
+
+// omitGeneratedColumns omits the generated columns from INSERT and UPDATE statements, with returning their values are read back
+func omitGeneratedColumns(returning, create bool) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Error != nil || db.Statement.Schema == nil {
+			return
+		}
+		fields := generatedFieldsOf(db.Statement.Schema)
+		if len(fields) == 0 {
+			return
+		}
+
+		for _, field := range fields {
+			db.Statement.Omits = append(db.Statement.Omits, field.DBName)
+		}
+
+		if !returning || !db.Statement.ReflectValue.CanAddr() {
+			return
+		}
+		if _, ok := db.Statement.Clauses["RETURNING"]; ok {
+			return
+		}
+
+		var columns []clause.Column
+		if create {
+			// gorm returns the fields with a default value unless there is a RETURNING clause already
+			for _, field := range db.Statement.Schema.FieldsWithDefaultDBValue {
+				columns = append(columns, clause.Column{Name: field.DBName})
+			}
+		}
+		for _, field := range fields {
+			columns = append(columns, clause.Column{Name: field.DBName})
+		}
+		db.Statement.AddClause(clause.Returning{Columns: columns})
+	}
+}
+
+func registerGeneratedColumnCallbacks(db *gorm.DB, returning bool) error {
+	callback := db.Callback()
+	if err := callback.Create().Before("gorm:create").Register("postgres:generated_columns", omitGeneratedColumns(returning, true)); err != nil {
+		return err
+	}
+	return callback.Update().Before("gorm:update").Register("postgres:generated_columns", omitGeneratedColumns(returning, false))
+}
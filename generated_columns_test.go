@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+type generatedLine struct {
+	ID       uint64
+	Price    float64
+	Quantity int
+	Total    float64 `gorm:"not null;generated:price * quantity"`
+	Label    string  `gorm:"generated:upper(name);virtual"`
+}
+
+func TestMigrator_FullDataTypeOf_generated(t *testing.T) {
+	s, err := schema.Parse(&generatedLine{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("failed to parse schema, got error %v", err)
+	}
+	dialector := Dialector{Config: &Config{}}
+	m := Migrator{migrator.Migrator{Config: migrator.Config{DB: &gorm.DB{Config: &gorm.Config{Dialector: dialector}}, Dialector: dialector}}}
+
+	tests := []struct {
+		name  string
+		field string
+		want  string
+	}{
+		{name: "it should define stored generated columns", field: "Total", want: "decimal NOT NULL GENERATED ALWAYS AS (price * quantity) STORED"},
+		{name: "it should define virtual generated columns", field: "Label", want: "text GENERATED ALWAYS AS (upper(name)) VIRTUAL"},
+		{name: "it should keep regular columns", field: "Price", want: "decimal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.FullDataTypeOf(s.LookUpField(tt.field)).SQL; got != tt.want {
+				t.Errorf("FullDataTypeOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeneratedColumnsCallbacks(t *testing.T) {
+	tx := dryRunDB(t)
+
+	line := generatedLine{Price: 2.5, Quantity: 4, Total: 10, Label: "stale"}
+	if sql := tx.Create(&line).Statement.SQL.String(); sql != `INSERT INTO "generated_lines" ("price","quantity") VALUES ($1,$2) RETURNING "id","total","label"` {
+		t.Errorf("expected the generated columns to be omitted and returned, got %v", sql)
+	}
+
+	line.ID = 1
+	if sql := tx.Save(&line).Statement.SQL.String(); sql != `UPDATE "generated_lines" SET "price"=$1,"quantity"=$2 WHERE "id" = $3 RETURNING "total","label"` {
+		t.Errorf("expected the generated columns to be omitted and returned, got %v", sql)
+	}
+}
+
+func TestMigrator_migrateGeneratedColumn(t *testing.T) {
+	stored := &ColumnType{GeneratedValue: sql.NullString{String: "price * 2", Valid: true}}
+	virtual := &ColumnType{GeneratedValue: sql.NullString{String: "price * quantity", Valid: true}, VirtualValue: true}
+
+	tests := []struct {
+		name       string
+		version    int64
+		current    *ColumnType
+		recreation bool
+		wantErr    bool
+		want       []string
+	}{
+		{
+			name:    "it should set the changed expression",
+			version: 170002,
+			current: stored,
+			want:    []string{`ALTER TABLE "generated_lines" ALTER COLUMN "total" SET EXPRESSION AS (price * quantity)`},
+		},
+		{
+			name:    "it should fail to change the expression before Postgres 17",
+			version: 160004,
+			current: stored,
+			wantErr: true,
+		},
+		{
+			name:       "it should add the column again before Postgres 17 with the recreation",
+			version:    160004,
+			current:    stored,
+			recreation: true,
+			want: []string{
+				`ALTER TABLE "generated_lines" DROP COLUMN "total"`,
+				`ALTER TABLE "generated_lines" ADD "total" decimal NOT NULL GENERATED ALWAYS AS (price * quantity) STORED`,
+			},
+		},
+		{
+			name:    "it should fail to switch between virtual and stored",
+			version: 180000,
+			current: virtual,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, connector := stubDB(t, func(query string) ([]string, [][]driver.Value, error) {
+				if strings.Contains(query, "server_version_num") {
+					return []string{"current_setting"}, [][]driver.Value{{tt.version}}, nil
+				}
+				return nil, nil, nil
+			})
+			if tt.recreation {
+				db = WithGeneratedColumnRecreation(db)
+			}
+			m := db.Migrator().(Migrator)
+			s, err := schema.Parse(&generatedLine{}, &sync.Map{}, db.NamingStrategy)
+			if err != nil {
+				t.Fatalf("failed to parse schema, got error %v", err)
+			}
+
+			if err := m.migrateGeneratedColumn(&generatedLine{}, s.LookUpField("Total"), tt.current); (err != nil) != tt.wantErr {
+				t.Errorf("migrateGeneratedColumn() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var altered []string
+			for _, statement := range connector.statements() {
+				if strings.HasPrefix(statement, "ALTER TABLE") {
+					altered = append(altered, statement)
+				}
+			}
+			if !reflect.DeepEqual(altered, tt.want) {
+				t.Errorf("migrateGeneratedColumn() executed %q, want %q", altered, tt.want)
+			}
+		})
+	}
+}
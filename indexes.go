@@ -0,0 +1,248 @@
+package postgres
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+// indexElementSql lists the key elements of the indexes of a table with their operator class and ordering,
+// the parameters of an operator class are stored as the options of the index attribute
+const indexElementSql = `
+SELECT
+	ci.relname AS index_name,
+	am.amname AS method,
+	pg_get_indexdef(i.indexrelid, k.n, true) AS expression,
+	i.indkey[k.n - 1] = 0 AS is_expression,
+	coalesce(oc.opcname, '') AS opclass,
+	coalesce(oc.opcdefault, true) AS default_opclass,
+	coalesce(array_to_string(ia.attoptions, ','), '') AS opclass_params,
+	i.indoption[k.n - 1] & 1 = 1 AS descending,
+	i.indoption[k.n - 1] & 2 = 2 AS nulls_first,
+	coalesce(array_to_string(ci.reloptions, ','), '') AS storage_parameters,
+	coalesce(ts.spcname, '') AS tablespace,
+	array_to_string(ARRAY(SELECT pg_get_indexdef(i.indexrelid, c.n, true) FROM generate_series(i.indnkeyatts + 1, i.indnatts) AS c(n)), ',') AS include,
+	pg_get_indexdef(i.indexrelid) LIKE '% NULLS NOT DISTINCT%' AS nulls_not_distinct
+FROM
+	pg_index i
+	JOIN pg_class ct ON ct.oid = i.indrelid
+	JOIN pg_class ci ON ci.oid = i.indexrelid
+	JOIN pg_am am ON am.oid = ci.relam
+	CROSS JOIN LATERAL generate_series(1, i.indnkeyatts) AS k(n)
+	LEFT JOIN pg_opclass oc ON oc.oid = i.indclass[k.n - 1]
+	LEFT JOIN pg_attribute ia ON ia.attrelid = i.indexrelid AND ia.attnum = k.n
+	LEFT JOIN pg_tablespace ts ON ts.oid = ci.reltablespace
+WHERE
+	ct.relname = ?
+	AND ct.relnamespace = (SELECT oid FROM pg_namespace WHERE nspname = ?)
+ORDER BY
+	ci.relname, k.n
+`
+
+// IndexElement a key element of an index, a column or an expression with its operator class and ordering
+//
+//	Name string `gorm:"index:,opclass:text_pattern_ops,sort:desc,nulls:last"`
+//	Code string `gorm:"index:,type:gist,opclass:gist_trgm_ops(siglen=32)"`
+type IndexElement struct {
+	// Expression the column or the expression indexed
+	Expression string
+	// Opclass the operator class, empty in a model for the default operator class of the column type
+	Opclass string
+	// OpclassParams the parameters of the operator class, e.g. `siglen=32`
+	OpclassParams string
+	Descending    bool
+	NullsFirst    bool
+
+	defaultOpclass bool
+	isExpression   bool
+}
+
+// IndexDefinition an index of a table returned by GetIndexes, with its access method, key elements and options
+type IndexDefinition struct {
+	*migrator.Index
+	Method   string
+	Elements []IndexElement
+	Options  IndexOptions
+}
+
+// indexElementRow a key element of an index as listed by indexElementSql
+type indexElementRow struct {
+	IndexName      string `gorm:"column:index_name"`
+	Method         string `gorm:"column:method"`
+	Expression     string `gorm:"column:expression"`
+	IsExpression   bool   `gorm:"column:is_expression"`
+	Opclass        string `gorm:"column:opclass"`
+	DefaultOpclass bool   `gorm:"column:default_opclass"`
+	OpclassParams  string `gorm:"column:opclass_params"`
+	Descending     bool   `gorm:"column:descending"`
+	NullsFirst     bool   `gorm:"column:nulls_first"`
+
+	StorageParameters string `gorm:"column:storage_parameters"`
+	Tablespace        string `gorm:"column:tablespace"`
+	Include           string `gorm:"column:include"`
+	NullsNotDistinct  bool   `gorm:"column:nulls_not_distinct"`
+}
+
+// parseOpclass splits the `opclass` setting of an index field into the operator class and its parameters
+//
+//	gist_trgm_ops(siglen=32)
+func parseOpclass(setting string) (opclass, params string) {
+	opclass = strings.TrimSpace(setting)
+	if idx := strings.Index(opclass, "("); idx != -1 && strings.HasSuffix(opclass, ")") {
+		opclass, params = strings.TrimSpace(opclass[:idx]), strings.TrimSpace(opclass[idx+1:len(opclass)-1])
+	}
+	return
+}
+
+// indexNulls returns the `NULLS FIRST` or `NULLS LAST` option of the `nulls` setting of an index field
+func indexNulls(setting string) string {
+	switch strings.ToUpper(strings.TrimSpace(setting)) {
+	case "FIRST":
+		return "NULLS FIRST"
+	case "LAST":
+		return "NULLS LAST"
+	}
+	return ""
+}
+
+// indexElementsOf returns the key elements of the index idx declared by the model of stmt
+func indexElementsOf(stmt *gorm.Statement, idx *schema.Index) []IndexElement {
+	elements := make([]IndexElement, 0, len(idx.Fields))
+	for _, opt := range idx.Fields {
+		settings := indexFieldSettings(stmt, opt.Field, idx.Name)
+		element := IndexElement{Expression: opt.DBName, Descending: strings.EqualFold(strings.TrimSpace(opt.Sort), "desc")}
+		if opt.Expression != "" {
+			element.Expression, element.isExpression = opt.Expression, true
+		}
+		element.Opclass, element.OpclassParams = parseOpclass(settings["OPCLASS"])
+
+		// nulls sort as larger than any value by default
+		switch indexNulls(settings["NULLS"]) {
+		case "NULLS FIRST":
+			element.NullsFirst = true
+		case "NULLS LAST":
+			element.NullsFirst = false
+		default:
+			element.NullsFirst = element.Descending
+		}
+		elements = append(elements, element)
+	}
+	return elements
+}
+
+// indexChanged returns whether the access method or the key elements of the index current differ from idx, the indexed
+// columns are compared by name, the indexed expressions aren't as the database rewrites them
+func indexChanged(stmt *gorm.Statement, idx *schema.Index, current *IndexDefinition) bool {
+	method := strings.ToLower(strings.TrimSpace(idx.Type))
+	if method == "" {
+		method = "btree"
+	}
+	if current.Method != "" && current.Method != method {
+		return true
+	}
+
+	elements := indexElementsOf(stmt, idx)
+	if len(elements) != len(current.Elements) {
+		return true
+	}
+	for i, element := range elements {
+		currentElement := current.Elements[i]
+		if !element.isExpression && (currentElement.isExpression || unquoteIdentifier(currentElement.Expression) != element.Expression) {
+			return true
+		}
+		opclass := element.Opclass
+		if idx := strings.LastIndex(opclass, "."); idx != -1 {
+			opclass = opclass[idx+1:]
+		}
+		if (opclass == "" && !currentElement.defaultOpclass) || (opclass != "" && !strings.EqualFold(opclass, currentElement.Opclass)) {
+			return true
+		}
+		if normalizeOpclassParams(element.OpclassParams) != normalizeOpclassParams(currentElement.OpclassParams) {
+			return true
+		}
+		if element.Descending != currentElement.Descending || element.NullsFirst != currentElement.NullsFirst {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeOpclassParams(params string) string {
+	return strings.ToLower(strings.Join(strings.Fields(params), ""))
+}
+
+// indexDefinitionOf returns the index name of indexes
+func indexDefinitionOf(indexes []gorm.Index, name string) *IndexDefinition {
+	for _, index := range indexes {
+		if definition, ok := index.(*IndexDefinition); ok && definition.Name() == name {
+			return definition
+		}
+	}
+	return nil
+}
+
+// indexElements returns the key elements of the indexes of the table of stmt by index name
+func (m Migrator) indexElements(stmt *gorm.Statement) (map[string]*IndexDefinition, error) {
+	var rows []indexElementRow
+	currentSchema, curTable := m.CurrentSchema(stmt, stmt.Table)
+	if err := m.queryRaw(indexElementSql, curTable, currentSchema).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	definitions := map[string]*IndexDefinition{}
+	for _, row := range rows {
+		definition, ok := definitions[row.IndexName]
+		if !ok {
+			definition = &IndexDefinition{Method: row.Method, Options: IndexOptions{
+				With:             parseStorageParameters(row.StorageParameters),
+				Tablespace:       row.Tablespace,
+				NullsNotDistinct: row.NullsNotDistinct,
+			}}
+			if row.Include != "" {
+				definition.Options.Include = strings.Split(row.Include, ",")
+			}
+			definitions[row.IndexName] = definition
+		}
+		definition.Elements = append(definition.Elements, IndexElement{
+			Expression:     row.Expression,
+			Opclass:        row.Opclass,
+			OpclassParams:  row.OpclassParams,
+			Descending:     row.Descending,
+			NullsFirst:     row.NullsFirst,
+			defaultOpclass: row.DefaultOpclass,
+			isExpression:   row.IsExpression,
+		})
+	}
+	return definitions, nil
+}
+
+// migrateIndex recreates the index current when the index idx declared by the model changed its access method, operator
+// classes, ordering or included columns, otherwise its storage options are altered, the index is dropped concurrently in
+// online mode as CreateIndex builds it
+func (m Migrator) migrateIndex(value interface{}, idx *schema.Index, current *IndexDefinition) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		options, declared := indexOptionsOf(stmt, idx.Name)
+		if indexChanged(stmt, idx, current) || (declared && indexOptionsChanged(options, current)) {
+			dropSQL := "DROP INDEX ?"
+			// indexes of partitioned tables can't be dropped concurrently
+			if _, online := onlineMigrationOf(m.DB); online {
+				if _, partitioned, _ := partitioningOf(value, stmt.Schema); !partitioned {
+					dropSQL = "DROP INDEX CONCURRENTLY ?"
+				}
+			}
+			if err := m.DB.Exec(dropSQL, m.qualifiedName(stmt, idx.Name)).Error; err != nil {
+				return err
+			}
+			return m.CreateIndex(value, idx.Name)
+		}
+		if !declared {
+			return nil
+		}
+
+		return m.alterStorage(
+			"INDEX", m.qualifiedName(stmt, idx.Name), options.With, current.Options.With, options.Tablespace, current.Options.Tablespace,
+		)
+	})
+}
@@ -0,0 +1,216 @@
+package postgres
+
+import (
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+type indexedUser struct {
+	ID    uint64
+	Name  string `gorm:"index:,opclass:text_pattern_ops,sort:desc,nulls:last"`
+	Code  string `gorm:"index:,type:gist,opclass:gist_trgm_ops(siglen = 32)"`
+	Email string `gorm:"index"`
+	Slug  string `gorm:"index:idx_indexed_users_slug,expression:lower(slug)"`
+}
+
+func indexedUserStatement(t *testing.T) (Migrator, *gorm.Statement) {
+	db := testDB(t, Config{})
+	s, err := schema.Parse(&indexedUser{}, &sync.Map{}, db.NamingStrategy)
+	if err != nil {
+		t.Fatalf("failed to parse schema, got error %v", err)
+	}
+	return db.Migrator().(Migrator), &gorm.Statement{DB: db, Schema: s, Table: s.Table}
+}
+
+func TestMigrator_buildIndexOptions_elements(t *testing.T) {
+	m, stmt := indexedUserStatement(t)
+
+	tests := []struct {
+		name  string
+		index string
+		want  []interface{}
+	}{
+		{name: "it should add the opclass, the sort and the nulls ordering", index: "idx_indexed_users_name", want: []interface{}{clause.Expr{SQL: `"name" text_pattern_ops desc NULLS LAST`}}},
+		{name: "it should add the opclass parameters", index: "idx_indexed_users_code", want: []interface{}{clause.Expr{SQL: `"code" gist_trgm_ops(siglen = 32)`}}},
+		{name: "it should add the column only", index: "idx_indexed_users_email", want: []interface{}{clause.Expr{SQL: `"email"`}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := stmt.Schema.LookIndex(tt.index)
+			if idx == nil {
+				t.Fatalf("failed to find index %v", tt.index)
+			}
+			if got := m.buildIndexOptions(idx.Fields, stmt, idx.Name); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildIndexOptions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_indexChanged(t *testing.T) {
+	_, stmt := indexedUserStatement(t)
+
+	tests := []struct {
+		name    string
+		index   string
+		current IndexDefinition
+		want    bool
+	}{
+		{
+			name:    "it should keep an index with the same elements",
+			index:   "idx_indexed_users_name",
+			current: IndexDefinition{Method: "btree", Elements: []IndexElement{{Expression: "name", Opclass: "text_pattern_ops", Descending: true}}},
+		},
+		{
+			name:    "it should recreate an index with another nulls ordering",
+			index:   "idx_indexed_users_name",
+			current: IndexDefinition{Method: "btree", Elements: []IndexElement{{Expression: "name", Opclass: "text_pattern_ops", Descending: true, NullsFirst: true}}},
+			want:    true,
+		},
+		{
+			name:    "it should recreate an index with another opclass",
+			index:   "idx_indexed_users_name",
+			current: IndexDefinition{Method: "btree", Elements: []IndexElement{{Expression: "name", Opclass: "text_ops", Descending: true, defaultOpclass: true}}},
+			want:    true,
+		},
+		{
+			name:    "it should keep an index with the same opclass parameters",
+			index:   "idx_indexed_users_code",
+			current: IndexDefinition{Method: "gist", Elements: []IndexElement{{Expression: "code", Opclass: "gist_trgm_ops", OpclassParams: "siglen=32"}}},
+		},
+		{
+			name:    "it should recreate an index with other opclass parameters",
+			index:   "idx_indexed_users_code",
+			current: IndexDefinition{Method: "gist", Elements: []IndexElement{{Expression: "code", Opclass: "gist_trgm_ops", OpclassParams: "siglen=12"}}},
+			want:    true,
+		},
+		{
+			name:    "it should keep an index with the default opclass",
+			index:   "idx_indexed_users_email",
+			current: IndexDefinition{Method: "btree", Elements: []IndexElement{{Expression: "email", Opclass: "text_ops", defaultOpclass: true}}},
+		},
+		{
+			name:    "it should recreate an index of another column",
+			index:   "idx_indexed_users_email",
+			current: IndexDefinition{Method: "btree", Elements: []IndexElement{{Expression: "name", Opclass: "text_ops", defaultOpclass: true}}},
+			want:    true,
+		},
+		{
+			name:    "it should compare the quoted column names",
+			index:   "idx_indexed_users_email",
+			current: IndexDefinition{Method: "btree", Elements: []IndexElement{{Expression: `"email"`, Opclass: "text_ops", defaultOpclass: true}}},
+		},
+		{
+			name:    "it should recreate an index of an expression declared on a column",
+			index:   "idx_indexed_users_email",
+			current: IndexDefinition{Method: "btree", Elements: []IndexElement{{Expression: "lower(email)", Opclass: "text_ops", defaultOpclass: true, isExpression: true}}},
+			want:    true,
+		},
+		{
+			name:    "it should keep an index of an expression rewritten by the database",
+			index:   "idx_indexed_users_slug",
+			current: IndexDefinition{Method: "btree", Elements: []IndexElement{{Expression: "lower(slug::text)", Opclass: "text_ops", defaultOpclass: true, isExpression: true}}},
+		},
+		{
+			name:    "it should recreate an index with another method",
+			index:   "idx_indexed_users_email",
+			current: IndexDefinition{Method: "hash", Elements: []IndexElement{{Expression: "email", Opclass: "text_ops", defaultOpclass: true}}},
+			want:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := stmt.Schema.LookIndex(tt.index)
+			if idx == nil {
+				t.Fatalf("failed to find index %v", tt.index)
+			}
+			if got := indexChanged(stmt, idx, &tt.current); got != tt.want {
+				t.Errorf("indexChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrator_migrateIndex(t *testing.T) {
+	current := &IndexDefinition{Method: "hash", Elements: []IndexElement{{Expression: "email", Opclass: "text_ops", defaultOpclass: true}}}
+
+	tests := []struct {
+		name   string
+		table  string
+		online bool
+		want   []string
+	}{
+		{
+			name: "it should drop and create the changed index",
+			want: []string{
+				`DROP INDEX "idx_indexed_users_email"`,
+				`CREATE INDEX IF NOT EXISTS "idx_indexed_users_email" ON "indexed_users" ("email")`,
+			},
+		},
+		{
+			name:   "it should drop and create the changed index concurrently online",
+			online: true,
+			want: []string{
+				`DROP INDEX CONCURRENTLY "idx_indexed_users_email"`,
+				`CREATE INDEX CONCURRENTLY IF NOT EXISTS "idx_indexed_users_email" ON "indexed_users" ("email")`,
+			},
+		},
+		{
+			name:  "it should qualify the dropped index with the schema of the table",
+			table: "app.indexed_users",
+			want: []string{
+				`DROP INDEX "app"."idx_indexed_users_email"`,
+				`CREATE INDEX IF NOT EXISTS "idx_indexed_users_email" ON "app"."indexed_users" ("email")`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &planRecorder{Interface: logger.Discard, plan: &migrationPlan{}}
+			db := dryRunDB(t).Session(&gorm.Session{Logger: recorder})
+			if tt.online {
+				db = WithOnlineMigration(db, OnlineMigration{})
+			}
+			if tt.table != "" {
+				db = db.Table(tt.table)
+			}
+			_, stmt := indexedUserStatement(t)
+			if err := db.Migrator().(Migrator).migrateIndex(&indexedUser{}, stmt.Schema.LookIndex("idx_indexed_users_email"), current); err != nil {
+				t.Fatalf("migrateIndex() error = %v", err)
+			}
+			if got := recorder.plan.sql; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("migrateIndex() executed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrator_CreateIndex_online(t *testing.T) {
+	db, connector := stubDB(t, func(query string) ([]string, [][]driver.Value, error) {
+		if strings.HasPrefix(query, "CREATE INDEX") {
+			return nil, nil, errors.New("canceling statement due to lock timeout")
+		}
+		return nil, nil, nil
+	})
+	db = WithOnlineMigration(db, OnlineMigration{}).Table("app.indexed_users")
+
+	if err := db.Migrator().CreateIndex(&indexedUser{}, "idx_indexed_users_email"); err == nil {
+		t.Fatalf("CreateIndex() error = nil, want the failed build")
+	}
+	want := []string{
+		`CREATE INDEX CONCURRENTLY IF NOT EXISTS "idx_indexed_users_email" ON "app"."indexed_users" ("email")`,
+		`DROP INDEX CONCURRENTLY IF EXISTS "app"."idx_indexed_users_email"`,
+	}
+	if got := connector.statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("CreateIndex() executed %q, want %q", got, want)
+	}
+}
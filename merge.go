@@ -0,0 +1,283 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	mergeTargetAlias = "target"
+	mergeSourceAlias = "source"
+)
+
+// serialTypes the integer types of the serial types, the serial types are no types to cast to
+var serialTypes = map[string]string{
+	"smallserial": "smallint",
+	"serial":      "integer",
+	"bigserial":   "bigint",
+}
+
+// Target references the column of the target row in the conditions of Merge
+func Target(column string) clause.Column {
+	return clause.Column{Table: mergeTargetAlias, Name: column}
+}
+
+// Source references the column of the source row in the conditions and actions of Merge
+func Source(column string) clause.Column {
+	return clause.Column{Table: mergeSourceAlias, Name: column}
+}
+
+// SourceAssignments assigns the columns of the source row to the same columns of the target row
+func SourceAssignments(columns ...string) clause.Set {
+	assignments := make(clause.Set, 0, len(columns))
+	for _, column := range columns {
+		assignments = append(assignments, clause.Assignment{Column: clause.Column{Name: column}, Value: Source(column)})
+	}
+	return assignments
+}
+
+// MergeAction the action of a `WHEN` clause of Merge, it deletes, updates, inserts or does nothing
+type MergeAction struct {
+	// Condition the additional condition of the `WHEN` clause
+	Condition clause.Expression
+	Delete    bool
+	Update    clause.Set
+	// Insert the columns inserted with the values of the source row
+	Insert []string
+}
+
+// Merge a `MERGE` statement, joining the target table with the source rows, a slice of models rendered as `VALUES`,
+// a subquery or a table, the merged rows are returned with Postgres 17 and later
+//
+//	db.Exec("?", postgres.Merge{
+//		Source:         users,
+//		WhenMatched:    []postgres.MergeAction{{Condition: clause.Expr{SQL: "? IS DISTINCT FROM ?", Vars: []interface{}{postgres.Target("name"), postgres.Source("name")}}, Update: postgres.SourceAssignments("name")}},
+//		WhenNotMatched: []postgres.MergeAction{{Insert: []string{"id", "name"}}},
+//	})
+type Merge struct {
+	// Table the target table, the table of the source models by default
+	Table  string
+	Source interface{}
+	// Columns the columns of the source models, all but the generated ones by default
+	Columns []string
+	// On the join condition, the primary keys of the source models by default
+	On                     clause.Expression
+	WhenMatched            []MergeAction
+	WhenNotMatched         []MergeAction
+	WhenNotMatchedBySource []MergeAction
+	// Returning the returned columns, e.g. `{Name: "merge_action()", Raw: true}` and `{Table: "target", Name: "*"}`
+	Returning []clause.Column
+}
+
+// Build builds the `MERGE` statement
+func (merge Merge) Build(builder clause.Builder) {
+	stmt, ok := builder.(*gorm.Statement)
+	if !ok {
+		builder.AddError(errors.New("failed to build MERGE outside of a statement"))
+		return
+	}
+
+	table, on := merge.Table, merge.On
+	var (
+		s       *schema.Schema
+		columns = merge.Columns
+		rows    = reflect.Indirect(reflect.ValueOf(merge.Source))
+	)
+	if rows.Kind() == reflect.Slice || rows.Kind() == reflect.Array {
+		sourceStmt := &gorm.Statement{DB: stmt.DB}
+		if err := sourceStmt.Parse(merge.Source); err != nil {
+			builder.AddError(err)
+			return
+		}
+		s = sourceStmt.Schema
+		if table == "" {
+			table = s.Table
+		}
+		if len(columns) == 0 {
+			columns = valuesColumnsOf(s)
+		}
+		if on == nil {
+			if on = primaryKeysJoin(s, mergeTargetAlias, mergeSourceAlias); on == nil {
+				builder.AddError(fmt.Errorf("failed to merge into %s without primary keys, On is required", table))
+				return
+			}
+		}
+	}
+	if table == "" || on == nil {
+		builder.AddError(errors.New("the Table and On of a MERGE from a subquery or a table are required"))
+		return
+	}
+
+	builder.WriteString("MERGE INTO ")
+	builder.WriteQuoted(clause.Table{Name: table})
+	builder.WriteString(" AS ")
+	builder.WriteQuoted(mergeTargetAlias)
+	builder.WriteString(" USING ")
+	switch source := merge.Source.(type) {
+	case string:
+		builder.WriteQuoted(clause.Table{Name: source})
+		builder.WriteString(" AS ")
+		builder.WriteQuoted(mergeSourceAlias)
+	case *gorm.DB:
+		builder.WriteByte('(')
+		builder.AddVar(builder, source)
+		builder.WriteString(") AS ")
+		builder.WriteQuoted(mergeSourceAlias)
+	default:
+		if s == nil {
+			builder.AddError(fmt.Errorf("unsupported MERGE source %T", merge.Source))
+			return
+		}
+		if err := writeValues(stmt, s, rows, columns, mergeSourceAlias); err != nil {
+			builder.AddError(err)
+			return
+		}
+	}
+	builder.WriteString(" ON ")
+	on.Build(builder)
+
+	writeMergeActions(builder, "MATCHED", merge.WhenMatched)
+	writeMergeActions(builder, "NOT MATCHED", merge.WhenNotMatched)
+	writeMergeActions(builder, "NOT MATCHED BY SOURCE", merge.WhenNotMatchedBySource)
+
+	if len(merge.Returning) > 0 {
+		builder.WriteString(" RETURNING ")
+		for idx, column := range merge.Returning {
+			if idx > 0 {
+				builder.WriteByte(',')
+			}
+			if column.Name == "*" && column.Table != "" && !column.Raw {
+				builder.WriteQuoted(column.Table)
+				builder.WriteString(".*")
+				continue
+			}
+			builder.WriteQuoted(column)
+		}
+	}
+}
+
+// writeMergeActions writes the `WHEN` clauses of actions
+func writeMergeActions(builder clause.Builder, when string, actions []MergeAction) {
+	for _, action := range actions {
+		builder.WriteString(" WHEN " + when)
+		if action.Condition != nil {
+			builder.WriteString(" AND ")
+			action.Condition.Build(builder)
+		}
+		builder.WriteString(" THEN ")
+
+		switch {
+		case action.Delete:
+			builder.WriteString("DELETE")
+		case len(action.Update) > 0:
+			builder.WriteString("UPDATE SET ")
+			action.Update.Build(builder)
+		case len(action.Insert) > 0:
+			builder.WriteString("INSERT (")
+			for idx, column := range action.Insert {
+				if idx > 0 {
+					builder.WriteByte(',')
+				}
+				builder.WriteQuoted(column)
+			}
+			builder.WriteString(") VALUES (")
+			for idx, column := range action.Insert {
+				if idx > 0 {
+					builder.WriteByte(',')
+				}
+				builder.WriteQuoted(Source(column))
+			}
+			builder.WriteByte(')')
+		default:
+			builder.WriteString("DO NOTHING")
+		}
+	}
+}
+
+// valuesColumnsOf returns the columns of s written by writeValues, all but the generated ones
+func valuesColumnsOf(s *schema.Schema) (columns []string) {
+	for _, dbName := range s.DBNames {
+		if _, _, generated := generatedColumnOf(s.FieldsByDBName[dbName]); !generated {
+			columns = append(columns, dbName)
+		}
+	}
+	return
+}
+
+// primaryKeysJoin returns the condition joining the primary keys of the rows left and right of s
+func primaryKeysJoin(s *schema.Schema, left, right string) clause.Expression {
+	if len(s.PrimaryFieldDBNames) == 0 {
+		return nil
+	}
+	exprs := make([]clause.Expression, 0, len(s.PrimaryFieldDBNames))
+	for _, dbName := range s.PrimaryFieldDBNames {
+		exprs = append(exprs, clause.Eq{Column: clause.Column{Table: left, Name: dbName}, Value: clause.Column{Table: right, Name: dbName}})
+	}
+	return clause.And(exprs...)
+}
+
+// writeValues writes rows, the models of s, as a `VALUES` list aliased as alias, the values are cast to the types of their
+// columns as Postgres would take them as text otherwise
+//
+//	(VALUES ($1::bigint,$2::text),($3::bigint,$4::text)) AS "source"("id","name")
+func writeValues(stmt *gorm.Statement, s *schema.Schema, rows reflect.Value, columns []string, alias string) error {
+	if rows.Len() == 0 {
+		return errors.New("failed to write the VALUES of no rows")
+	}
+
+	fields := make([]*schema.Field, 0, len(columns))
+	casts := make([]string, 0, len(columns))
+	dataTypeOf := stmt.DB.Dialector.DataTypeOf
+	if m, ok := stmt.DB.Migrator().(Migrator); ok {
+		dataTypeOf = m.DataTypeOf
+	}
+	for _, column := range columns {
+		field := s.LookUpField(column)
+		if field == nil || field.DBName == "" {
+			return fmt.Errorf("failed to find the column %s of %s", column, s.Name)
+		}
+		dataType := dataTypeOf(field)
+		if integerType, ok := serialTypes[strings.ToLower(dataType)]; ok {
+			dataType = integerType
+		}
+		fields = append(fields, field)
+		casts = append(casts, dataType)
+	}
+
+	stmt.WriteString("(VALUES ")
+	for i := 0; i < rows.Len(); i++ {
+		row := reflect.Indirect(rows.Index(i))
+		if i > 0 {
+			stmt.WriteByte(',')
+		}
+		stmt.WriteByte('(')
+		for idx, field := range fields {
+			if idx > 0 {
+				stmt.WriteByte(',')
+			}
+			value, _ := field.ValueOf(stmt.Context, row)
+			stmt.AddVar(stmt, value)
+			if casts[idx] != "" {
+				stmt.WriteString("::" + casts[idx])
+			}
+		}
+		stmt.WriteByte(')')
+	}
+	stmt.WriteString(") AS ")
+	stmt.WriteQuoted(alias)
+	stmt.WriteByte('(')
+	for idx, field := range fields {
+		if idx > 0 {
+			stmt.WriteByte(',')
+		}
+		stmt.WriteQuoted(field.DBName)
+	}
+	stmt.WriteByte(')')
+	return nil
+}
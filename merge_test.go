@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"testing"
+
+	"gorm.io/gorm/clause"
+)
+
+type mergedUser struct {
+	ID   uint64
+	Name string
+	Age  int
+}
+
+func TestMerge(t *testing.T) {
+	tx := dryRunDB(t)
+	users := []mergedUser{{ID: 1, Name: "jinzhu", Age: 18}, {ID: 2, Name: "gorm", Age: 10}}
+
+	tests := []struct {
+		name     string
+		merge    Merge
+		want     string
+		wantVars int
+	}{
+		{
+			name: "it should merge the models as values",
+			merge: Merge{
+				Source:         users,
+				WhenMatched:    []MergeAction{{Condition: clause.Expr{SQL: "? IS DISTINCT FROM ?", Vars: []interface{}{Target("name"), Source("name")}}, Update: SourceAssignments("name", "age")}},
+				WhenNotMatched: []MergeAction{{Insert: []string{"id", "name", "age"}}},
+			},
+			want:     `MERGE INTO "merged_users" AS "target" USING (VALUES ($1::bigint,$2::text,$3::bigint),($4::bigint,$5::text,$6::bigint)) AS "source"("id","name","age") ON "target"."id" = "source"."id" WHEN MATCHED AND "target"."name" IS DISTINCT FROM "source"."name" THEN UPDATE SET "name"="source"."name","age"="source"."age" WHEN NOT MATCHED THEN INSERT ("id","name","age") VALUES ("source"."id","source"."name","source"."age")`,
+			wantVars: 6,
+		},
+		{
+			name: "it should merge the selected columns and return the merged rows",
+			merge: Merge{
+				Source:                 &users,
+				Columns:                []string{"id", "age"},
+				WhenMatched:            []MergeAction{{Condition: clause.Expr{SQL: "? = 0", Vars: []interface{}{Source("age")}}, Delete: true}, {}},
+				WhenNotMatchedBySource: []MergeAction{{Update: clause.Set{{Column: clause.Column{Name: "age"}, Value: 0}}}},
+				Returning:              []clause.Column{{Name: "merge_action()", Raw: true}, {Table: "target", Name: "*"}},
+			},
+			want:     `MERGE INTO "merged_users" AS "target" USING (VALUES ($1::bigint,$2::bigint),($3::bigint,$4::bigint)) AS "source"("id","age") ON "target"."id" = "source"."id" WHEN MATCHED AND "source"."age" = 0 THEN DELETE WHEN MATCHED THEN DO NOTHING WHEN NOT MATCHED BY SOURCE THEN UPDATE SET "age"=$5 RETURNING merge_action(),"target".*`,
+			wantVars: 5,
+		},
+		{
+			name: "it should merge a subquery",
+			merge: Merge{
+				Table:       "users",
+				Source:      tx.Table("staged_users").Where("batch = ?", 3),
+				On:          clause.Eq{Column: Target("id"), Value: Source("id")},
+				WhenMatched: []MergeAction{{Update: SourceAssignments("name")}},
+			},
+			want:     `MERGE INTO "users" AS "target" USING (SELECT * FROM "staged_users" WHERE batch = $1) AS "source" ON "target"."id" = "source"."id" WHEN MATCHED THEN UPDATE SET "name"="source"."name"`,
+			wantVars: 1,
+		},
+		{
+			name:     "it should merge a table",
+			merge:    Merge{Table: "users", Source: "staged_users", On: clause.Eq{Column: Target("id"), Value: Source("id")}, WhenMatched: []MergeAction{{Delete: true}}},
+			want:     `MERGE INTO "users" AS "target" USING "staged_users" AS "source" ON "target"."id" = "source"."id" WHEN MATCHED THEN DELETE`,
+			wantVars: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt := tx.Exec("?", tt.merge).Statement
+			if stmt.SQL.String() != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, stmt.SQL.String())
+			}
+			if len(stmt.Vars) != tt.wantVars {
+				t.Errorf("expected %d vars, got %v", tt.wantVars, stmt.Vars)
+			}
+		})
+	}
+
+	if err := tx.Exec("?", Merge{Source: "staged_users"}).Error; err == nil {
+		t.Errorf("expected an error merging a table without On")
+	}
+}
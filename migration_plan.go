@@ -0,0 +1,188 @@
+package postgres
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// StatementKind classifies the impact of a planned DDL statement on a live database
+type StatementKind string
+
+const (
+	// StatementSafe only takes short-lived locks
+	StatementSafe StatementKind = "safe"
+	// StatementLocking holds a lock blocking reads or writes for a time proportional to the table size
+	StatementLocking StatementKind = "locking"
+	// StatementDestructive drops data
+	StatementDestructive StatementKind = "destructive"
+	// StatementTableRewrite rewrites the whole table under an ACCESS EXCLUSIVE lock
+	StatementTableRewrite StatementKind = "table-rewriting"
+)
+
+// MigrationStatement a statement AutoMigrate would execute
+type MigrationStatement struct {
+	SQL  string
+	Kind StatementKind
+}
+
+var (
+	whitespacePattern   = regexp.MustCompile(`\s+`)
+	identifierPattern   = `((?:"(?:[^"]|"")+"|[\w$]+)(?:\.(?:"(?:[^"]|"")+"|[\w$]+))?)`
+	createTablePattern  = regexp.MustCompile(`^CREATE (?:UNLOGGED |TEMPORARY |TEMP )?TABLE (?:IF NOT EXISTS )?` + identifierPattern)
+	alterTablePattern   = regexp.MustCompile(`^ALTER TABLE (?:IF EXISTS )?(?:ONLY )?` + identifierPattern)
+	createIndexPattern  = regexp.MustCompile(`^CREATE (?:UNIQUE )?INDEX .*? ON (?:ONLY )?` + identifierPattern)
+	commentOnPattern    = regexp.MustCompile(`^COMMENT ON COLUMN ` + identifierPattern + `\.`)
+	volatileDefault     = regexp.MustCompile(`\bDEFAULT (?:CLOCK_TIMESTAMP|RANDOM|GEN_RANDOM_UUID|UUID_GENERATE_V4|NEXTVAL)\b`)
+	serialColumnPattern = regexp.MustCompile(`\b(?:SMALL|BIG)?SERIAL\b`)
+)
+
+// Plan returns the ordered statements AutoMigrate would execute for values, without executing them
+//
+//	statements, err := db.Migrator().(postgres.Migrator).Plan(&User{}, &Pet{})
+//	for _, statement := range statements {
+//		if statement.Kind == postgres.StatementDestructive {
+//			...
+//		}
+//	}
+func (m Migrator) Plan(values ...interface{}) ([]MigrationStatement, error) {
+	recorder := &planRecorder{Interface: m.DB.Logger, plan: &migrationPlan{}}
+	tx := m.DB.Session(&gorm.Session{DryRun: true, Logger: recorder})
+	if err := tx.Migrator().AutoMigrate(values...); err != nil {
+		return nil, err
+	}
+	return recorder.plan.statements(), nil
+}
+
+type migrationPlan struct {
+	mu  sync.Mutex
+	sql []string
+}
+
+func (plan *migrationPlan) record(sql string) {
+	plan.mu.Lock()
+	defer plan.mu.Unlock()
+	plan.sql = append(plan.sql, sql)
+}
+
+func (plan *migrationPlan) statements() []MigrationStatement {
+	plan.mu.Lock()
+	defer plan.mu.Unlock()
+
+	var (
+		statements    = make([]MigrationStatement, 0, len(plan.sql))
+		createdTables = map[string]bool{}
+	)
+	for _, sql := range plan.sql {
+		kind := classifyStatement(sql)
+		normalized := normalizeStatement(sql)
+		if matches := createTablePattern.FindStringSubmatch(normalized); len(matches) > 1 {
+			createdTables[unquoteIdentifier(matches[1])] = true
+		} else if kind != StatementDestructive && createdTables[statementTable(normalized)] {
+			// the table is created by this plan, nothing can be blocked or lost yet
+			kind = StatementSafe
+		}
+		statements = append(statements, MigrationStatement{SQL: sql, Kind: kind})
+	}
+	return statements
+}
+
+// planRecorder records the DDL statements executed in a dry run session
+type planRecorder struct {
+	logger.Interface
+	plan *migrationPlan
+}
+
+func (recorder *planRecorder) LogMode(level logger.LogLevel) logger.Interface {
+	return &planRecorder{Interface: recorder.Interface.LogMode(level), plan: recorder.plan}
+}
+
+func (recorder *planRecorder) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	sql, _ := fc()
+	recorder.plan.record(sql)
+	recorder.Interface.Trace(ctx, begin, fc, err)
+}
+
+// queryLogger returns the logger used for introspection queries, which should never be recorded into a plan
+func queryLogger(l logger.Interface) logger.Interface {
+	if recorder, ok := l.(*planRecorder); ok {
+		return recorder.Interface
+	}
+	return l
+}
+
+// classifyStatement classifies a DDL statement by the locks it takes and the data it may lose
+func classifyStatement(sql string) StatementKind {
+	sql = normalizeStatement(sql)
+
+	switch {
+	case strings.HasPrefix(sql, "DROP TABLE"), strings.HasPrefix(sql, "DROP SCHEMA"),
+		strings.HasPrefix(sql, "DROP SEQUENCE"), strings.HasPrefix(sql, "DROP MATERIALIZED VIEW"),
+		strings.HasPrefix(sql, "TRUNCATE"), strings.HasPrefix(sql, "DELETE"):
+		return StatementDestructive
+	case strings.HasPrefix(sql, "DROP INDEX"):
+		if strings.HasPrefix(sql, "DROP INDEX CONCURRENTLY") {
+			return StatementSafe
+		}
+		return StatementLocking
+	case strings.HasPrefix(sql, "CREATE INDEX"), strings.HasPrefix(sql, "CREATE UNIQUE INDEX"):
+		if concurrentlyPattern.MatchString(sql) {
+			return StatementSafe
+		}
+		return StatementLocking
+	case strings.HasPrefix(sql, "REFRESH MATERIALIZED VIEW"):
+		if concurrentlyPattern.MatchString(sql) {
+			return StatementSafe
+		}
+		return StatementLocking
+	case strings.HasPrefix(sql, "VACUUM FULL"), strings.HasPrefix(sql, "CLUSTER"):
+		return StatementTableRewrite
+	case strings.HasPrefix(sql, "ALTER TABLE"):
+		return classifyAlterTable(sql)
+	}
+	return StatementSafe
+}
+
+func classifyAlterTable(sql string) StatementKind {
+	switch {
+	case strings.Contains(sql, " DROP COLUMN "):
+		return StatementDestructive
+	case strings.Contains(sql, " TYPE "), strings.Contains(sql, " SET TABLESPACE "),
+		strings.Contains(sql, " SET LOGGED"), strings.Contains(sql, " SET UNLOGGED"):
+		return StatementTableRewrite
+	case strings.Contains(sql, " ADD ") && !strings.Contains(sql, " ADD CONSTRAINT ") && !strings.Contains(sql, " ADD PRIMARY KEY") && !strings.Contains(sql, " ADD UNIQUE"):
+		// adding a column is a catalog only change, unless every row gets a computed value
+		if volatileDefault.MatchString(sql) || serialColumnPattern.MatchString(sql) || strings.Contains(sql, " STORED") {
+			return StatementTableRewrite
+		}
+		return StatementSafe
+	case strings.Contains(sql, " NOT VALID"), strings.Contains(sql, " VALIDATE CONSTRAINT "):
+		return StatementSafe
+	case strings.Contains(sql, " ADD "), strings.Contains(sql, " SET NOT NULL"):
+		return StatementLocking
+	}
+	return StatementSafe
+}
+
+func normalizeStatement(sql string) string {
+	return strings.ToUpper(strings.TrimSpace(whitespacePattern.ReplaceAllString(sql, " ")))
+}
+
+// statementTable returns the table a normalized statement operates on
+func statementTable(sql string) string {
+	for _, pattern := range []*regexp.Regexp{alterTablePattern, createIndexPattern, commentOnPattern} {
+		if matches := pattern.FindStringSubmatch(sql); len(matches) > 1 {
+			return unquoteIdentifier(matches[1])
+		}
+	}
+	return ""
+}
+
+func unquoteIdentifier(name string) string {
+	return strings.ReplaceAll(name, `"`, "")
+}
@@ -0,0 +1,53 @@
+package postgres
+
+import "testing"
+
+func Test_classifyStatement(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want StatementKind
+	}{
+		{name: "it should be safe to create a table", sql: `CREATE TABLE "users" ("id" bigserial,PRIMARY KEY ("id"))`, want: StatementSafe},
+		{name: "it should be safe to comment", sql: `COMMENT ON COLUMN "users"."name" IS 'name'`, want: StatementSafe},
+		{name: "it should be safe to add a column", sql: `ALTER TABLE "users" ADD "age" bigint`, want: StatementSafe},
+		{name: "it should be safe to add a column with a stable default", sql: `ALTER TABLE "users" ADD "created_at" timestamptz DEFAULT now()`, want: StatementSafe},
+		{name: "it should rewrite to add a column with a volatile default", sql: `ALTER TABLE "users" ADD "uuid" uuid DEFAULT gen_random_uuid()`, want: StatementTableRewrite},
+		{name: "it should rewrite to add a serial column", sql: `ALTER TABLE "users" ADD "seq" bigserial`, want: StatementTableRewrite},
+		{name: "it should rewrite to change a column type", sql: `ALTER TABLE "users" ALTER COLUMN "age" TYPE bigint USING "age"::bigint`, want: StatementTableRewrite},
+		{name: "it should lock to set not null", sql: `ALTER TABLE "users" ALTER COLUMN "name" SET NOT NULL`, want: StatementLocking},
+		{name: "it should lock to add a constraint", sql: `ALTER TABLE "pets" ADD CONSTRAINT "fk_users_pets" FOREIGN KEY ("user_id") REFERENCES "users"("id")`, want: StatementLocking},
+		{name: "it should be safe to add a constraint not valid", sql: `ALTER TABLE "pets" ADD CONSTRAINT "fk_users_pets" FOREIGN KEY ("user_id") REFERENCES "users"("id") NOT VALID`, want: StatementSafe},
+		{name: "it should be safe to validate a constraint", sql: `ALTER TABLE "pets" VALIDATE CONSTRAINT "fk_users_pets"`, want: StatementSafe},
+		{name: "it should lock to create an index", sql: `CREATE INDEX IF NOT EXISTS "idx_users_name" ON "users" ("name")`, want: StatementLocking},
+		{name: "it should be safe to create an index concurrently", sql: `CREATE INDEX CONCURRENTLY IF NOT EXISTS "idx_users_name" ON "users" ("name")`, want: StatementSafe},
+		{name: "it should destroy to drop a column", sql: `ALTER TABLE "users" DROP COLUMN "age"`, want: StatementDestructive},
+		{name: "it should destroy to drop a table", sql: `DROP TABLE IF EXISTS "users" CASCADE`, want: StatementDestructive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyStatement(tt.sql); got != tt.want {
+				t.Errorf("classifyStatement() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_migrationPlan_statements(t *testing.T) {
+	plan := &migrationPlan{}
+	plan.record(`CREATE TABLE "users" ("id" bigserial,"name" text,PRIMARY KEY ("id"))`)
+	plan.record(`CREATE INDEX IF NOT EXISTS "idx_users_name" ON "users" ("name")`)
+	plan.record(`CREATE INDEX IF NOT EXISTS "idx_pets_name" ON "pets" ("name")`)
+	plan.record(`ALTER TABLE "public"."pets" ALTER COLUMN "name" SET NOT NULL`)
+
+	want := []StatementKind{StatementSafe, StatementSafe, StatementLocking, StatementLocking}
+	statements := plan.statements()
+	if len(statements) != len(want) {
+		t.Fatalf("statements() returns %v statements, want %v", len(statements), len(want))
+	}
+	for idx, statement := range statements {
+		if statement.Kind != want[idx] {
+			t.Errorf("statements()[%d] kind = %v, want %v", idx, statement.Kind, want[idx])
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const defaultMigrationsTable = "schema_migrations"
+
+// Migration a versioned migration, changes are made either by the Up/Down functions or by the UpSQL/DownSQL statements
+type Migration struct {
+	ID      string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+	UpSQL   string
+	DownSQL string
+	// WithoutTransaction runs the migration outside of a transaction, e.g. for `CREATE INDEX CONCURRENTLY`
+	WithoutTransaction bool
+}
+
+// AppliedMigration a migration recorded in the history table
+type AppliedMigration struct {
+	ID        string
+	AppliedAt time.Time
+}
+
+// MigrationsConfig configures the versioned migrations runner
+type MigrationsConfig struct {
+	// TableName is the history table, defaults to `schema_migrations`
+	TableName string
+	// LockKey is the advisory lock key serializing concurrent runners, defaults to a key derived from TableName
+	LockKey int64
+}
+
+// Migrations runs versioned migrations in the order they are registered, recording them in a history table.
+// Concurrent runners, e.g. several instances of a service starting at the same time, are serialized with `pg_advisory_lock`
+//
+//	migrations := postgres.NewMigrations(db, postgres.MigrationsConfig{})
+//	migrations.Register(
+//		&postgres.Migration{ID: "202401010000_create_users", UpSQL: "CREATE TABLE users (id bigserial PRIMARY KEY)", DownSQL: "DROP TABLE users"},
+//		&postgres.Migration{ID: "202401020000_add_users_name", Up: func(tx *gorm.DB) error { return tx.Migrator().AddColumn(&User{}, "Name") }},
+//	)
+//	err := migrations.Migrate()
+type Migrations struct {
+	db         *gorm.DB
+	config     MigrationsConfig
+	migrations []*Migration
+}
+
+func NewMigrations(db *gorm.DB, config MigrationsConfig) *Migrations {
+	if config.TableName == "" {
+		config.TableName = defaultMigrationsTable
+	}
+	if config.LockKey == 0 {
+		config.LockKey = AdvisoryLockKey("gorm:migrations:" + config.TableName)
+	}
+	return &Migrations{db: db, config: config}
+}
+
+// Register registers migrations, IDs must be unique and migrations must have an Up function or UpSQL statements
+func (ms *Migrations) Register(migrations ...*Migration) error {
+	for _, migration := range migrations {
+		if migration.ID == "" {
+			return errors.New("migration id is required")
+		}
+		if migration.Up == nil && migration.UpSQL == "" {
+			return fmt.Errorf("migration %s has neither Up nor UpSQL", migration.ID)
+		}
+		if ms.lookUp(migration.ID) != nil {
+			return fmt.Errorf("duplicated migration id %s", migration.ID)
+		}
+		ms.migrations = append(ms.migrations, migration)
+	}
+	return nil
+}
+
+// Migrate applies all pending migrations
+func (ms *Migrations) Migrate() error {
+	return ms.migrateTo("")
+}
+
+// MigrateTo applies the pending migrations registered before id, and id itself, nothing is applied when id is applied already
+func (ms *Migrations) MigrateTo(id string) error {
+	if ms.lookUp(id) == nil {
+		return fmt.Errorf("failed to find migration %s", id)
+	}
+	return ms.migrateTo(id)
+}
+
+// Rollback reverts the last applied migration
+func (ms *Migrations) Rollback() error {
+	return ms.run(func(conn *gorm.DB, applied []AppliedMigration) error {
+		if len(applied) == 0 {
+			return nil
+		}
+		return ms.down(conn, applied[len(applied)-1].ID)
+	})
+}
+
+// RollbackTo reverts applied migrations until id is the last applied one
+func (ms *Migrations) RollbackTo(id string) error {
+	return ms.run(func(conn *gorm.DB, applied []AppliedMigration) error {
+		found := false
+		for _, migration := range applied {
+			found = found || migration.ID == id
+		}
+		if !found {
+			return fmt.Errorf("migration %s is not applied", id)
+		}
+
+		for i := len(applied) - 1; i >= 0 && applied[i].ID != id; i-- {
+			if err := ms.down(conn, applied[i].ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Applied returns the applied migrations, in the order they were applied
+func (ms *Migrations) Applied() (applied []AppliedMigration, err error) {
+	err = ms.run(func(conn *gorm.DB, migrations []AppliedMigration) error {
+		applied = migrations
+		return nil
+	})
+	return
+}
+
+// Pending returns the registered migrations not applied yet
+func (ms *Migrations) Pending() (pending []*Migration, err error) {
+	err = ms.run(func(conn *gorm.DB, applied []AppliedMigration) error {
+		pending = pendingMigrations(ms.migrations, applied)
+		return nil
+	})
+	return
+}
+
+func (ms *Migrations) migrateTo(id string) error {
+	return ms.run(func(conn *gorm.DB, applied []AppliedMigration) error {
+		for _, migration := range pendingMigrationsTo(ms.migrations, applied, id) {
+			if err := ms.up(conn, migration); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// run calls fc on a connection holding the migrations advisory lock, with the applied migrations
+func (ms *Migrations) run(fc func(conn *gorm.DB, applied []AppliedMigration) error) error {
+	return WithAdvisoryLock(ms.db, ms.config.LockKey, func(conn *gorm.DB) error {
+		if err := conn.Exec(
+			"CREATE TABLE IF NOT EXISTS ? (id varchar(255) PRIMARY KEY, applied_at timestamptz NOT NULL DEFAULT now())", ms.table(),
+		).Error; err != nil {
+			return err
+		}
+
+		var applied []AppliedMigration
+		if err := conn.Raw("SELECT id, applied_at FROM ? ORDER BY applied_at, id", ms.table()).Scan(&applied).Error; err != nil {
+			return err
+		}
+		return fc(conn, applied)
+	})
+}
+
+func (ms *Migrations) up(conn *gorm.DB, migration *Migration) error {
+	return ms.transaction(conn, migration, func(tx *gorm.DB) error {
+		var err error
+		if migration.Up != nil {
+			err = migration.Up(tx)
+		} else {
+			err = tx.Exec(migration.UpSQL).Error
+		}
+		if err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", migration.ID, err)
+		}
+		return tx.Exec("INSERT INTO ? (id) VALUES (?)", ms.table(), migration.ID).Error
+	})
+}
+
+func (ms *Migrations) down(conn *gorm.DB, id string) error {
+	migration := ms.lookUp(id)
+	if migration == nil {
+		return fmt.Errorf("failed to find migration %s", id)
+	}
+	if migration.Down == nil && migration.DownSQL == "" {
+		return fmt.Errorf("migration %s can't be rolled back", id)
+	}
+
+	return ms.transaction(conn, migration, func(tx *gorm.DB) error {
+		var err error
+		if migration.Down != nil {
+			err = migration.Down(tx)
+		} else {
+			err = tx.Exec(migration.DownSQL).Error
+		}
+		if err != nil {
+			return fmt.Errorf("failed to roll back migration %s: %w", migration.ID, err)
+		}
+		return tx.Exec("DELETE FROM ? WHERE id = ?", ms.table(), migration.ID).Error
+	})
+}
+
+func (ms *Migrations) transaction(conn *gorm.DB, migration *Migration, fc func(tx *gorm.DB) error) error {
+	if migration.WithoutTransaction {
+		return fc(conn)
+	}
+	return conn.Transaction(fc)
+}
+
+func (ms *Migrations) table() clause.Table {
+	return clause.Table{Name: ms.config.TableName}
+}
+
+func (ms *Migrations) lookUp(id string) *Migration {
+	for _, migration := range ms.migrations {
+		if migration.ID == id {
+			return migration
+		}
+	}
+	return nil
+}
+
+func pendingMigrations(migrations []*Migration, applied []AppliedMigration) (pending []*Migration) {
+	appliedIDs := make(map[string]bool, len(applied))
+	for _, migration := range applied {
+		appliedIDs[migration.ID] = true
+	}
+
+	for _, migration := range migrations {
+		if !appliedIDs[migration.ID] {
+			pending = append(pending, migration)
+		}
+	}
+	return
+}
+
+// pendingMigrationsTo returns the pending migrations registered up to and including id, none when id is applied already,
+// all pending migrations without id
+func pendingMigrationsTo(migrations []*Migration, applied []AppliedMigration, id string) []*Migration {
+	if id != "" {
+		for _, migration := range applied {
+			if migration.ID == id {
+				return nil
+			}
+		}
+		for idx, migration := range migrations {
+			if migration.ID == id {
+				migrations = migrations[:idx+1]
+				break
+			}
+		}
+	}
+	return pendingMigrations(migrations, applied)
+}
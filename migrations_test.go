@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestMigrations_Register(t *testing.T) {
+	migrations := NewMigrations(nil, MigrationsConfig{})
+	if migrations.config.TableName != "schema_migrations" {
+		t.Errorf("expected default table name schema_migrations, got %v", migrations.config.TableName)
+	}
+	if migrations.config.LockKey == 0 {
+		t.Errorf("expected a default lock key")
+	}
+
+	if err := migrations.Register(&Migration{ID: "1", UpSQL: "SELECT 1"}, &Migration{ID: "2", Up: func(tx *gorm.DB) error { return nil }}); err != nil {
+		t.Fatalf("failed to register migrations, got error %v", err)
+	}
+	if err := migrations.Register(&Migration{ID: "2", UpSQL: "SELECT 2"}); err == nil {
+		t.Errorf("expected an error registering a duplicated migration id")
+	}
+	if err := migrations.Register(&Migration{UpSQL: "SELECT 3"}); err == nil {
+		t.Errorf("expected an error registering a migration without id")
+	}
+	if err := migrations.Register(&Migration{ID: "3", DownSQL: "SELECT 3"}); err == nil {
+		t.Errorf("expected an error registering a migration without Up nor UpSQL")
+	}
+	if len(migrations.migrations) != 2 {
+		t.Errorf("expected 2 registered migrations, got %v", len(migrations.migrations))
+	}
+}
+
+func Test_pendingMigrations(t *testing.T) {
+	var (
+		first  = &Migration{ID: "202401010000_create_users"}
+		second = &Migration{ID: "202401020000_create_pets"}
+		third  = &Migration{ID: "202401030000_add_users_name"}
+	)
+
+	tests := []struct {
+		name    string
+		applied []AppliedMigration
+		want    []*Migration
+	}{
+		{name: "it should return all migrations when none is applied", want: []*Migration{first, second, third}},
+		{name: "it should skip applied migrations", applied: []AppliedMigration{{ID: first.ID}, {ID: third.ID}}, want: []*Migration{second}},
+		{name: "it should ignore unknown applied migrations", applied: []AppliedMigration{{ID: "unknown"}}, want: []*Migration{first, second, third}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pendingMigrations([]*Migration{first, second, third}, tt.applied); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pendingMigrations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_pendingMigrationsTo(t *testing.T) {
+	var (
+		first  = &Migration{ID: "202401010000_create_users"}
+		second = &Migration{ID: "202401020000_create_pets"}
+		third  = &Migration{ID: "202401030000_add_users_name"}
+	)
+
+	tests := []struct {
+		name    string
+		applied []AppliedMigration
+		id      string
+		want    []*Migration
+	}{
+		{name: "it should return the migrations up to the target", id: second.ID, want: []*Migration{first, second}},
+		{name: "it should return nothing when the target is applied", applied: []AppliedMigration{{ID: second.ID}}, id: second.ID},
+		{name: "it should return the migrations before an applied target", applied: []AppliedMigration{{ID: second.ID}}, id: third.ID, want: []*Migration{first, third}},
+		{name: "it should return all pending migrations without target", applied: []AppliedMigration{{ID: first.ID}}, want: []*Migration{second, third}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pendingMigrationsTo([]*Migration{first, second, third}, tt.applied, tt.id); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pendingMigrationsTo() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
@@ -33,7 +35,7 @@ FROM
 WHERE
 	a.attnum = ANY(i.indkey)
 	AND con.oid IS NULL
-	AND ct.relkind = 'r'
+	AND ct.relkind IN ('r', 'p', 'm')
 	AND ct.relname = ?
 `
 
@@ -79,12 +81,180 @@ type Migrator struct {
 
 // select querys ignore dryrun
 func (m Migrator) queryRaw(sql string, values ...interface{}) (tx *gorm.DB) {
+	return m.queryTx().Raw(sql, values...)
+}
+
+func (m Migrator) queryTx() *gorm.DB {
 	queryTx := m.DB
 	if m.DB.DryRun {
-		queryTx = m.DB.Session(&gorm.Session{})
+		queryTx = m.DB.Session(&gorm.Session{Logger: queryLogger(m.DB.Logger)})
 		queryTx.DryRun = false
 	}
-	return queryTx.Raw(sql, values...)
+	// catalog queries don't depend on run-time settings, and Rows can't apply them outside of a transaction
+	if len(statementSettings(queryTx)) > 0 {
+		ctx := contextWithoutSettings(queryTx.Statement.Context)
+		if schemaName := m.defaultSchema(); schemaName != "" {
+			ctx = context.WithValue(ctx, schemaKey{}, schemaName)
+		}
+		queryTx = queryTx.WithContext(ctx)
+	}
+	return queryTx
+}
+
+func (m Migrator) GetQueryAndExecTx() (queryTx, execTx *gorm.DB) {
+	// planning, record the statements instead of printing them
+	if _, ok := m.DB.Logger.(*planRecorder); ok {
+		return m.queryTx(), m.DB.Session(&gorm.Session{})
+	}
+	return m.Migrator.GetQueryAndExecTx()
+}
+
+// AutoMigrate auto migrate values
+func (m Migrator) AutoMigrate(values ...interface{}) (err error) {
+	if key, ok := migrationLockOf(m.DB); ok && !m.DB.DryRun {
+		lock, lockErr := AcquireAdvisoryLock(m.DB, key)
+		if lockErr != nil {
+			return lockErr
+		}
+		defer func() {
+			if unlockErr := lock.Unlock(); err == nil {
+				err = unlockErr
+			}
+		}()
+	}
+
+	if transactionalMigrationOf(m.DB) && !m.DB.DryRun {
+		return m.autoMigrateInTransaction(values...)
+	}
+	return m.autoMigrate(values...)
+}
+
+func (m Migrator) autoMigrate(values ...interface{}) error {
+	for _, value := range m.ReorderModels(values, true) {
+		queryTx, execTx := m.GetQueryAndExecTx()
+		if migrated, err := execTx.Migrator().(Migrator).migrateView(value); err != nil {
+			return err
+		} else if migrated {
+			continue
+		}
+
+		if !queryTx.Migrator().HasTable(value) {
+			if err := execTx.Migrator().CreateTable(value); err != nil {
+				return err
+			}
+		} else if err := m.migrateTable(value, queryTx, execTx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateTable migrates the columns, constraints and indexes of an existing table
+func (m Migrator) migrateTable(value interface{}, queryTx, execTx *gorm.DB) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if stmt.Schema == nil {
+			return errors.New("failed to get schema")
+		}
+
+		if err := execTx.Migrator().(Migrator).createRequiredExtensions(value); err != nil {
+			return err
+		}
+
+		columnTypes, err := queryTx.Migrator().ColumnTypes(value)
+		if err != nil {
+			return err
+		}
+		var (
+			parseIndexes          = stmt.Schema.ParseIndexes()
+			parseCheckConstraints = stmt.Schema.ParseCheckConstraints()
+		)
+		for _, dbName := range stmt.Schema.DBNames {
+			var foundColumn gorm.ColumnType
+
+			for _, columnType := range columnTypes {
+				if columnType.Name() == dbName {
+					foundColumn = columnType
+					break
+				}
+			}
+
+			if foundColumn == nil {
+				// not found, add column
+				if err = execTx.Migrator().AddColumn(value, dbName); err != nil {
+					return err
+				}
+			} else {
+				// found, smartly migrate
+				field := stmt.Schema.FieldsByDBName[dbName]
+				if err = execTx.Migrator().MigrateColumn(value, field, foundColumn); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := execTx.Migrator().(Migrator).migrateTableOptions(value); err != nil {
+			return err
+		}
+
+		constraints, err := queryTx.Migrator().(Migrator).GetConstraints(value)
+		if err != nil {
+			return err
+		}
+
+		if !m.DB.DisableForeignKeyConstraintWhenMigrating && !m.DB.IgnoreRelationshipsWhenMigrating {
+			for _, rel := range stmt.Schema.Relationships.Relations {
+				if rel.Field.IgnoreMigration {
+					continue
+				}
+				if constraint := rel.ParseConstraint(); constraint != nil && constraint.Schema == stmt.Schema {
+					changed := func(current ConstraintDefinition) bool { return foreignKeyChanged(current, constraint) }
+					if err := execTx.Migrator().(Migrator).migrateConstraint(value, constraint.Name, constraints, changed); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		// Postgres rewrites the stored check expressions, e.g. IN as = ANY and BETWEEN as two comparisons, the checks are only
+		// created when missing
+		for _, chk := range parseCheckConstraints {
+			if err := execTx.Migrator().(Migrator).migrateConstraint(value, chk.Name, constraints, nil); err != nil {
+				return err
+			}
+		}
+
+		if err := execTx.Migrator().(Migrator).migrateExclusionConstraints(value, constraints); err != nil {
+			return err
+		}
+
+		indexes, err := queryTx.Migrator().GetIndexes(value)
+		if err != nil {
+			return err
+		}
+		for _, idx := range parseIndexes {
+			if current := indexDefinitionOf(indexes, idx.Name); current != nil {
+				if err := execTx.Migrator().(Migrator).migrateIndex(value, &idx, current); err != nil {
+					return err
+				}
+			} else if !queryTx.Migrator().HasIndex(value, idx.Name) {
+				if err := execTx.Migrator().CreateIndex(value, idx.Name); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := execTx.Migrator().(Migrator).migrateSearchIndexes(value); err != nil {
+			return err
+		}
+
+		if err := execTx.Migrator().(Migrator).migrateComments(value, true); err != nil {
+			return err
+		}
+		if err := execTx.Migrator().(Migrator).migrateRowSecurity(value, true); err != nil {
+			return err
+		}
+		return execTx.Migrator().(Migrator).migrateTriggers(value, true)
+	})
 }
 
 func (m Migrator) CurrentDatabase() (name string) {
@@ -93,6 +263,12 @@ func (m Migrator) CurrentDatabase() (name string) {
 }
 
 func (m Migrator) BuildIndexOptions(opts []schema.IndexOption, stmt *gorm.Statement) (results []interface{}) {
+	return m.buildIndexOptions(opts, stmt, "")
+}
+
+// buildIndexOptions builds the elements of the index name, with the operator class and its parameters of their `opclass`
+// tag key and the nulls ordering of their `nulls` tag key
+func (m Migrator) buildIndexOptions(opts []schema.IndexOption, stmt *gorm.Statement, name string) (results []interface{}) {
 	for _, opt := range opts {
 		str := stmt.Quote(opt.DBName)
 		if opt.Expression != "" {
@@ -103,14 +279,59 @@ func (m Migrator) BuildIndexOptions(opts []schema.IndexOption, stmt *gorm.Statem
 			str += " COLLATE " + opt.Collate
 		}
 
+		settings := indexFieldSettings(stmt, opt.Field, name)
+		if opclass := strings.TrimSpace(settings["OPCLASS"]); opclass != "" {
+			str += " " + opclass
+		}
+
 		if opt.Sort != "" {
 			str += " " + opt.Sort
 		}
+
+		if nulls := indexNulls(settings["NULLS"]); nulls != "" {
+			str += " " + nulls
+		}
 		results = append(results, clause.Expr{SQL: str})
 	}
 	return
 }
 
+// indexFieldSettings returns the settings of field's index tag declaring the index name, of its first index tag without name,
+// gorm ignores the keys it doesn't know like `opclass`
+//
+//	Name string `gorm:"index:,type:gin,opclass:gin_trgm_ops"`
+func indexFieldSettings(stmt *gorm.Statement, field *schema.Field, name string) map[string]string {
+	if field == nil {
+		return nil
+	}
+	for _, value := range strings.Split(field.Tag.Get("gorm"), ";") {
+		v := strings.Split(value, ":")
+		if k := strings.TrimSpace(strings.ToUpper(v[0])); k != "INDEX" && k != "UNIQUEINDEX" {
+			continue
+		}
+
+		var (
+			tag       = strings.Join(v[1:], ":")
+			indexName = tag
+			settings  = schema.ParseTagSetting(strings.Join(strings.Split(tag, ",")[1:], ","), ",")
+		)
+		if idx := strings.Index(tag, ","); idx != -1 {
+			indexName = tag[0:idx]
+		}
+		if indexName == "" && field.Schema != nil && stmt.DB != nil && stmt.DB.Config != nil && stmt.DB.NamingStrategy != nil {
+			subName := field.Name
+			if composite := settings["COMPOSITE"]; composite != "" && composite != "COMPOSITE" {
+				subName = composite
+			}
+			indexName = stmt.DB.NamingStrategy.IndexName(field.Schema.Table, subName)
+		}
+		if name == "" || indexName == name {
+			return settings
+		}
+	}
+	return nil
+}
+
 func (m Migrator) HasIndex(value interface{}, name string) bool {
 	var count int64
 	m.RunWithValue(value, func(stmt *gorm.Statement) error {
@@ -132,42 +353,67 @@ func (m Migrator) CreateIndex(value interface{}, name string) error {
 	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
 		if stmt.Schema != nil {
 			if idx := stmt.Schema.LookIndex(name); idx != nil {
-				opts := m.BuildIndexOptions(idx.Fields, stmt)
-				values := []interface{}{clause.Column{Name: idx.Name}, m.CurrentTable(stmt), opts}
+				return m.createIndex(value, stmt, idx)
+			}
+		}
 
-				createIndexSQL := "CREATE "
-				if idx.Class != "" {
-					createIndexSQL += idx.Class + " "
-				}
-				createIndexSQL += "INDEX "
+		return fmt.Errorf("failed to create index with name %v", name)
+	})
+}
 
-				hasConcurrentOption := strings.TrimSpace(strings.ToUpper(idx.Option)) == "CONCURRENTLY"
-				if hasConcurrentOption {
-					createIndexSQL += "CONCURRENTLY "
-				}
+// createIndex creates the index idx of value's table, concurrently in online mode, with its options and its comment
+func (m Migrator) createIndex(value interface{}, stmt *gorm.Statement, idx *schema.Index) error {
+	opts := m.buildIndexOptions(idx.Fields, stmt, idx.Name)
+	values := []interface{}{clause.Column{Name: idx.Name}, m.CurrentTable(stmt), opts}
 
-				createIndexSQL += "IF NOT EXISTS ? ON ?"
+	createIndexSQL := "CREATE "
+	if idx.Class != "" {
+		createIndexSQL += idx.Class + " "
+	}
+	createIndexSQL += "INDEX "
 
-				if idx.Type != "" {
-					createIndexSQL += " USING " + idx.Type + "(?)"
-				} else {
-					createIndexSQL += " ?"
-				}
+	hasConcurrentOption := strings.TrimSpace(strings.ToUpper(idx.Option)) == "CONCURRENTLY"
+	_, online := onlineMigrationOf(m.DB)
+	// indexes can't be created concurrently on partitioned tables
+	if _, partitioned, _ := partitioningOf(value, stmt.Schema); partitioned {
+		online = false
+	}
+	if hasConcurrentOption || online {
+		createIndexSQL += "CONCURRENTLY "
+	}
 
-				if idx.Option != "" && !hasConcurrentOption {
-					createIndexSQL += " " + idx.Option
-				}
+	createIndexSQL += "IF NOT EXISTS ? ON ?"
 
-				if idx.Where != "" {
-					createIndexSQL += " WHERE " + idx.Where
-				}
+	if idx.Type != "" {
+		createIndexSQL += " USING " + idx.Type + "(?)"
+	} else {
+		createIndexSQL += " ?"
+	}
 
-				return m.DB.Exec(createIndexSQL, values...).Error
-			}
+	if idx.Option != "" && !hasConcurrentOption {
+		createIndexSQL += " " + idx.Option
+	}
+
+	if options, ok := indexOptionsOf(stmt, idx.Name); ok {
+		createIndexSQL += options.clauses(stmt)
+	}
+
+	if idx.Where != "" {
+		createIndexSQL += " WHERE " + idx.Where
+	}
+
+	if err := m.DB.Exec(createIndexSQL, values...).Error; err != nil {
+		// a failed concurrent build leaves an invalid index behind
+		if online {
+			m.DB.Exec("DROP INDEX CONCURRENTLY IF EXISTS ?", m.qualifiedName(stmt, idx.Name))
 		}
+		return err
+	}
 
-		return fmt.Errorf("failed to create index with name %v", name)
-	})
+	if idx.Comment != "" {
+		return m.commentIndex(stmt, idx.Name, unquoteComment(idx.Comment))
+	}
+	return nil
 }
 
 func (m Migrator) RenameIndex(value interface{}, oldName, newName string) error {
@@ -195,14 +441,21 @@ func (m Migrator) DropIndex(value interface{}, name string) error {
 
 func (m Migrator) GetTables() (tableList []string, err error) {
 	currentSchema, _ := m.CurrentSchema(m.DB.Statement, "")
-	return tableList, m.queryRaw("SELECT table_name FROM information_schema.tables WHERE table_schema = ? AND table_type = ?", currentSchema, "BASE TABLE").Scan(&tableList).Error
+	return tableList, m.queryRaw(
+		"SELECT c.relname FROM pg_catalog.pg_class c JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = ? AND c.relkind IN ('r', 'p')", currentSchema,
+	).Scan(&tableList).Error
 }
 
 func (m Migrator) CreateTable(values ...interface{}) (err error) {
-	if err = m.Migrator.CreateTable(values...); err != nil {
-		return
-	}
 	for _, value := range m.ReorderModels(values, false) {
+		if err = m.createRequiredExtensions(value); err != nil {
+			return
+		}
+
+		if err = m.createTable(value); err != nil {
+			return
+		}
+
 		if err = m.RunWithValue(value, func(stmt *gorm.Statement) error {
 			if stmt.Schema != nil {
 				for _, fieldName := range stmt.Schema.DBNames {
@@ -210,7 +463,7 @@ func (m Migrator) CreateTable(values ...interface{}) (err error) {
 					if field.Comment != "" {
 						if err := m.DB.Exec(
 							"COMMENT ON COLUMN ?.? IS ?",
-							m.CurrentTable(stmt), clause.Column{Name: field.DBName}, gorm.Expr(m.Migrator.Dialector.Explain("$1", field.Comment)),
+							m.CurrentTable(stmt), clause.Column{Name: field.DBName}, m.commentExpr(unquoteComment(field.Comment)),
 						).Error; err != nil {
 							return err
 						}
@@ -221,15 +474,70 @@ func (m Migrator) CreateTable(values ...interface{}) (err error) {
 		}); err != nil {
 			return
 		}
+
+		if err = m.migrateExclusionConstraints(value, nil); err != nil {
+			return
+		}
+
+		if err = m.migrateSearchIndexes(value); err != nil {
+			return
+		}
+
+		if err = m.migrateComments(value, false); err != nil {
+			return
+		}
+
+		if err = m.migrateRowSecurity(value, false); err != nil {
+			return
+		}
+
+		if err = m.migrateTriggers(value, false); err != nil {
+			return
+		}
 	}
 	return
 }
 
+// createTable creates value's table, as a partitioned table when value declares a partitioning
+func (m Migrator) createTable(value interface{}) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		db, err := partitionTableOptions(m.DB, stmt, value)
+		if err != nil {
+			return err
+		}
+		db = tableStorageOptions(db, stmt)
+
+		// the base migrator creates foreign keys inline, without the Postgres specific options
+		foreignKeys := m.foreignKeysWithOptions(stmt)
+		if len(foreignKeys) > 0 {
+			config := *db.Config
+			config.DisableForeignKeyConstraintWhenMigrating = true
+			db = db.Session(&gorm.Session{})
+			db.Config = &config
+		}
+
+		creator := m
+		creator.DB = db
+		if err := creator.Migrator.CreateTable(value); err != nil {
+			return err
+		}
+
+		for _, constraint := range foreignKeys {
+			if err := m.CreateConstraint(value, constraint.Name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func (m Migrator) HasTable(value interface{}) bool {
 	var count int64
 	m.RunWithValue(value, func(stmt *gorm.Statement) error {
 		currentSchema, curTable := m.CurrentSchema(stmt, stmt.Table)
-		return m.queryRaw("SELECT count(*) FROM information_schema.tables WHERE table_schema = ? AND table_name = ? AND table_type = ?", currentSchema, curTable, "BASE TABLE").Scan(&count).Error
+		return m.queryRaw(
+			"SELECT count(*) FROM pg_catalog.pg_class c JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = ? AND c.relname = ? AND c.relkind IN ('r', 'p')", currentSchema, curTable,
+		).Scan(&count).Error
 	})
 	return count > 0
 }
@@ -259,7 +567,7 @@ func (m Migrator) AddColumn(value interface{}, field string) error {
 				if field.Comment != "" {
 					if err := m.DB.Exec(
 						"COMMENT ON COLUMN ?.? IS ?",
-						m.CurrentTable(stmt), clause.Column{Name: field.DBName}, gorm.Expr(m.Migrator.Dialector.Explain("$1", field.Comment)),
+						m.CurrentTable(stmt), clause.Column{Name: field.DBName}, m.commentExpr(unquoteComment(field.Comment)),
 					).Error; err != nil {
 						return err
 					}
@@ -293,7 +601,13 @@ func (m Migrator) HasColumn(value interface{}, field string) bool {
 func (m Migrator) MigrateColumn(value interface{}, field *schema.Field, columnType gorm.ColumnType) error {
 	// skip primary field
 	if !field.PrimaryKey {
-		if err := m.Migrator.MigrateColumn(value, field, columnType); err != nil {
+		// compare the comment as it is stored
+		unquoted := *field
+		unquoted.Comment = unquoteComment(field.Comment)
+		if err := m.Migrator.MigrateColumn(value, &unquoted, columnType); err != nil {
+			return err
+		}
+		if err := m.migrateGeneratedColumn(value, field, columnType); err != nil {
 			return err
 		}
 	}
@@ -308,12 +622,10 @@ func (m Migrator) MigrateColumn(value interface{}, field *schema.Field, columnTy
 		checkSQL += "(SELECT oid FROM pg_catalog.pg_namespace WHERE nspname = ?))"
 		m.queryRaw(checkSQL, values...).Scan(&description)
 
-		comment := strings.Trim(field.Comment, "'")
-		comment = strings.Trim(comment, `"`)
-		if field.Comment != "" && comment != description {
+		if comment := unquoteComment(field.Comment); field.Comment != "" && comment != description {
 			if err := m.DB.Exec(
 				"COMMENT ON COLUMN ?.? IS ?",
-				m.CurrentTable(stmt), clause.Column{Name: field.DBName}, gorm.Expr(m.Migrator.Dialector.Explain("$1", field.Comment)),
+				m.CurrentTable(stmt), clause.Column{Name: field.DBName}, m.commentExpr(unquoteComment(field.Comment)),
 			).Error; err != nil {
 				return err
 			}
@@ -334,7 +646,12 @@ func (m Migrator) AlterColumn(value interface{}, field string) error {
 				var fieldColumnType *migrator.ColumnType
 				for _, columnType := range columnTypes {
 					if columnType.Name() == field.DBName {
-						fieldColumnType, _ = columnType.(*migrator.ColumnType)
+						switch c := columnType.(type) {
+						case *ColumnType:
+							fieldColumnType = c.migratorColumnType
+						case *migrator.ColumnType:
+							fieldColumnType = c
+						}
 					}
 				}
 				if fieldColumnType == nil {
@@ -384,7 +701,11 @@ func (m Migrator) AlterColumn(value interface{}, field string) error {
 
 				if null, _ := fieldColumnType.Nullable(); null == field.NotNull {
 					if field.NotNull {
-						if err := m.DB.Exec("ALTER TABLE ? ALTER COLUMN ? SET NOT NULL", m.CurrentTable(stmt), clause.Column{Name: field.DBName}).Error; err != nil {
+						if _, online := onlineMigrationOf(m.DB); online {
+							if err := m.setNotNullOnline(stmt, field); err != nil {
+								return err
+							}
+						} else if err := m.DB.Exec("ALTER TABLE ? ALTER COLUMN ? SET NOT NULL", m.CurrentTable(stmt), clause.Column{Name: field.DBName}).Error; err != nil {
 							return err
 						}
 					} else {
@@ -432,10 +753,36 @@ func (m Migrator) AlterColumn(value interface{}, field string) error {
 	return nil
 }
 
+// setNotNullOnline adds NOT NULL without holding an ACCESS EXCLUSIVE lock during the table scan,
+// `SET NOT NULL` skips the scan when a validated `CHECK (column IS NOT NULL)` constraint exists
+func (m Migrator) setNotNullOnline(stmt *gorm.Statement, field *schema.Field) error {
+	checker := clause.Column{Name: m.DB.NamingStrategy.CheckerName(stmt.Table, field.DBName+"_not_null")}
+	column := clause.Column{Name: field.DBName}
+
+	if err := m.DB.Exec("ALTER TABLE ? DROP CONSTRAINT IF EXISTS ?", m.CurrentTable(stmt), checker).Error; err != nil {
+		return err
+	}
+	if err := m.DB.Exec("ALTER TABLE ? ADD CONSTRAINT ? CHECK (? IS NOT NULL) NOT VALID", m.CurrentTable(stmt), checker, column).Error; err != nil {
+		return err
+	}
+	if err := m.DB.Exec("ALTER TABLE ? VALIDATE CONSTRAINT ?", m.CurrentTable(stmt), checker).Error; err != nil {
+		return err
+	}
+	if err := m.DB.Exec("ALTER TABLE ? ALTER COLUMN ? SET NOT NULL", m.CurrentTable(stmt), column).Error; err != nil {
+		return err
+	}
+	return m.DB.Exec("ALTER TABLE ? DROP CONSTRAINT ?", m.CurrentTable(stmt), checker).Error
+}
+
 func (m Migrator) modifyColumn(stmt *gorm.Statement, field *schema.Field, targetType clause.Expr, existingColumn *migrator.ColumnType) error {
 	alterSQL := "ALTER TABLE ? ALTER COLUMN ? TYPE ? USING ?::?"
 	isUncastableDefaultValue := false
 
+	if _, _, ok := generatedColumnOf(field); ok {
+		// the values of a generated column are computed again, USING isn't allowed
+		return m.DB.Exec("ALTER TABLE ? ALTER COLUMN ? TYPE ?", m.CurrentTable(stmt), clause.Column{Name: field.DBName}, targetType).Error
+	}
+
 	if targetType.SQL == "boolean" {
 		switch existingColumn.DatabaseTypeName() {
 		case "int2", "int8", "numeric":
@@ -455,6 +802,43 @@ func (m Migrator) modifyColumn(stmt *gorm.Statement, field *schema.Field, target
 	return nil
 }
 
+func (m Migrator) CreateConstraint(value interface{}, name string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		constraint, table := m.GuessConstraintInterfaceAndTable(stmt, name)
+		if constraint == nil {
+			return nil
+		}
+
+		vars := []interface{}{clause.Table{Name: table}}
+		if stmt.TableExpr != nil {
+			vars[0] = stmt.TableExpr
+		}
+		sql, values := constraint.Build()
+
+		var notValid bool
+		if foreignKey, ok := constraint.(*schema.Constraint); ok {
+			options := foreignKeyOptionsOf(foreignKey)
+			sql, values = buildForeignKey(foreignKey, options)
+			notValid = options.NotValid
+		}
+
+		switch constraint.(type) {
+		case *schema.Constraint, *schema.CheckConstraint:
+			if _, online := onlineMigrationOf(m.DB); online || notValid {
+				// add without scanning the table, then validate with a SHARE UPDATE EXCLUSIVE lock only
+				if err := m.DB.Exec("ALTER TABLE ? ADD "+sql+" NOT VALID", append(vars, values...)...).Error; err != nil {
+					return err
+				}
+				if notValid {
+					return nil
+				}
+				return m.validateConstraint(vars[0], constraint.GetName())
+			}
+		}
+		return m.DB.Exec("ALTER TABLE ? ADD "+sql, append(vars, values...)...).Error
+	})
+}
+
 func (m Migrator) HasConstraint(value interface{}, name string) bool {
 	var count int64
 	m.RunWithValue(value, func(stmt *gorm.Statement) error {
@@ -465,7 +849,7 @@ func (m Migrator) HasConstraint(value interface{}, name string) bool {
 		currentSchema, curTable := m.CurrentSchema(stmt, table)
 
 		return m.queryRaw(
-			"SELECT count(*) FROM INFORMATION_SCHEMA.table_constraints WHERE table_schema = ? AND table_name = ? AND constraint_name = ?",
+			"SELECT count(*) FROM pg_catalog.pg_constraint con JOIN pg_catalog.pg_class c ON c.oid = con.conrelid JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = ? AND c.relname = ? AND con.conname = ?",
 			currentSchema, curTable, name,
 		).Scan(&count).Error
 	})
@@ -476,11 +860,12 @@ func (m Migrator) HasConstraint(value interface{}, name string) bool {
 func (m Migrator) ColumnTypes(value interface{}) (columnTypes []gorm.ColumnType, err error) {
 	columnTypes = make([]gorm.ColumnType, 0)
 	err = m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		generatedColumns := map[string]*ColumnType{}
 		var (
 			currentDatabase      = m.DB.Migrator().CurrentDatabase()
 			currentSchema, table = m.CurrentSchema(stmt, stmt.Table)
 			columns, err         = m.queryRaw(
-				"SELECT c.column_name, c.is_nullable = 'YES', c.udt_name, c.character_maximum_length, c.numeric_precision, c.numeric_precision_radix, c.numeric_scale, c.datetime_precision, 8 * typlen, c.column_default, pd.description, c.identity_increment FROM information_schema.columns AS c JOIN pg_type AS pgt ON c.udt_name = pgt.typname LEFT JOIN pg_catalog.pg_description as pd ON pd.objsubid = c.ordinal_position AND pd.objoid = (SELECT oid FROM pg_catalog.pg_class WHERE relname = c.table_name AND relnamespace = (SELECT oid FROM pg_catalog.pg_namespace WHERE nspname = c.table_schema)) where table_catalog = ? AND table_schema = ? AND table_name = ?",
+				"SELECT c.column_name, c.is_nullable = 'YES', c.udt_name, c.character_maximum_length, c.numeric_precision, c.numeric_precision_radix, c.numeric_scale, c.datetime_precision, 8 * typlen, c.column_default, pd.description, c.identity_increment, c.generation_expression FROM information_schema.columns AS c JOIN pg_type AS pgt ON c.udt_name = pgt.typname LEFT JOIN pg_catalog.pg_description as pd ON pd.objsubid = c.ordinal_position AND pd.objoid = (SELECT oid FROM pg_catalog.pg_class WHERE relname = c.table_name AND relnamespace = (SELECT oid FROM pg_catalog.pg_namespace WHERE nspname = c.table_schema)) where table_catalog = ? AND table_schema = ? AND table_name = ?",
 				currentDatabase, currentSchema, table).Rows()
 		)
 
@@ -498,11 +883,12 @@ func (m Migrator) ColumnTypes(value interface{}) (columnTypes []gorm.ColumnType,
 				radixValue        sql.NullInt64
 				typeLenValue      sql.NullInt64
 				identityIncrement sql.NullString
+				generation        sql.NullString
 			)
 
 			err = columns.Scan(
 				&column.NameValue, &column.NullableValue, &column.DataTypeValue, &column.LengthValue, &column.DecimalSizeValue,
-				&radixValue, &column.ScaleValue, &datetimePrecision, &typeLenValue, &column.DefaultValueValue, &column.CommentValue, &identityIncrement,
+				&radixValue, &column.ScaleValue, &datetimePrecision, &typeLenValue, &column.DefaultValueValue, &column.CommentValue, &identityIncrement, &generation,
 			)
 			if err != nil {
 				return err
@@ -525,6 +911,10 @@ func (m Migrator) ColumnTypes(value interface{}) (columnTypes []gorm.ColumnType,
 				column.DecimalSizeValue = datetimePrecision
 			}
 
+			if generation.Valid {
+				generatedColumns[column.NameValue.String] = &ColumnType{migratorColumnType: column, GeneratedValue: generation}
+			}
+
 			columnTypes = append(columnTypes, column)
 		}
 		columns.Close()
@@ -591,14 +981,17 @@ func (m Migrator) ColumnTypes(value interface{}) (columnTypes []gorm.ColumnType,
 
 		// check column type
 		{
-			dataTypeRows, err := m.queryRaw(`SELECT a.attname as column_name, format_type(a.atttypid, a.atttypmod) AS data_type	FROM pg_attribute a JOIN pg_class b ON a.attrelid = b.oid AND relnamespace = (SELECT oid FROM pg_catalog.pg_namespace WHERE nspname = ?)	WHERE a.attnum > 0	AND NOT a.attisdropped	AND b.relname = ?`, currentSchema, table).Rows()
+			dataTypeRows, err := m.queryRaw(`SELECT a.attname as column_name, format_type(a.atttypid, a.atttypmod) AS data_type, a.attgenerated::text	FROM pg_attribute a JOIN pg_class b ON a.attrelid = b.oid AND relnamespace = (SELECT oid FROM pg_catalog.pg_namespace WHERE nspname = ?)	WHERE a.attnum > 0	AND NOT a.attisdropped	AND b.relname = ?`, currentSchema, table).Rows()
 			if err != nil {
 				return err
 			}
 
 			for dataTypeRows.Next() {
-				var name, dataType string
-				dataTypeRows.Scan(&name, &dataType)
+				var name, dataType, generated string
+				dataTypeRows.Scan(&name, &dataType, &generated)
+				if generatedColumn, ok := generatedColumns[name]; ok {
+					generatedColumn.VirtualValue = generated == "v"
+				}
 				for _, c := range columnTypes {
 					mc := c.(*migrator.ColumnType)
 					if mc.NameValue.String == name {
@@ -616,6 +1009,12 @@ func (m Migrator) ColumnTypes(value interface{}) (columnTypes []gorm.ColumnType,
 			dataTypeRows.Close()
 		}
 
+		for idx, columnType := range columnTypes {
+			if generatedColumn, ok := generatedColumns[columnType.Name()]; ok {
+				columnTypes[idx] = generatedColumn
+			}
+		}
+
 		return err
 	})
 	return
@@ -627,7 +1026,7 @@ func (m Migrator) GetRows(currentSchema interface{}, table interface{}) (*sql.Ro
 		name = fmt.Sprintf("%v.%v", currentSchema, table)
 	}
 
-	return m.DB.Session(&gorm.Session{}).Table(name).Limit(1).Scopes(func(d *gorm.DB) *gorm.DB {
+	return m.queryTx().Session(&gorm.Session{}).Table(name).Limit(1).Scopes(func(d *gorm.DB) *gorm.DB {
 		dialector, _ := m.Dialector.(Dialector)
 		// use simple protocol
 		if !m.DB.PrepareStmt && (dialector.Config != nil && (dialector.Config.DriverName == "" || dialector.Config.DriverName == "pgx")) {
@@ -649,9 +1048,47 @@ func (m Migrator) CurrentSchema(stmt *gorm.Statement, table string) (interface{}
 			return strings.TrimPrefix(tables[0], `"`), table
 		}
 	}
+
+	if schemaName := m.defaultSchema(); schemaName != "" {
+		return schemaName, table
+	}
 	return clause.Expr{SQL: "CURRENT_SCHEMA()"}, table
 }
 
+// defaultSchema returns the schema set by WithSearchPath, the schema of the tenant or the first schema of Config.SearchPath when
+// it is applied to the connections
+func (m Migrator) defaultSchema() string {
+	if schemaName := SchemaFromContext(m.DB.Statement.Context); schemaName != "" {
+		return schemaName
+	}
+	dialector, ok := dialectorOf(m.Dialector)
+	if !ok {
+		return ""
+	}
+	if tenant := TenantFromContext(m.DB.Statement.Context); tenant != "" {
+		return dialector.tenantSchema(tenant)
+	}
+	if config := dialector.Config; config != nil && config.Conn == nil && config.DriverName == "" && len(config.SearchPath) > 0 {
+		// `$user` resolves to a schema named like the current user, leave it to CURRENT_SCHEMA()
+		if schemaName := config.SearchPath[0]; !strings.HasPrefix(schemaName, "$") {
+			return schemaName
+		}
+	}
+	return ""
+}
+
+// qualifiedName qualifies name, e.g. of an index or a partition, with the schema of the statement table when it is explicit
+func (m Migrator) qualifiedName(stmt *gorm.Statement, name string) clause.Table {
+	if !strings.Contains(name, ".") {
+		if currentSchema, _ := m.CurrentSchema(stmt, stmt.Table); currentSchema != nil {
+			if schemaName, ok := currentSchema.(string); ok {
+				return clause.Table{Name: schemaName + "." + name}
+			}
+		}
+	}
+	return clause.Table{Name: name}
+}
+
 func (m Migrator) CreateSequence(tx *gorm.DB, stmt *gorm.Statement, field *schema.Field,
 	serialDatabaseType string) (err error) {
 
@@ -721,13 +1158,13 @@ func (m Migrator) DeleteSequence(tx *gorm.DB, stmt *gorm.Statement, field *schem
 
 func (m Migrator) getColumnSequenceName(tx *gorm.DB, stmt *gorm.Statement, field *schema.Field) (
 	sequenceName string, err error) {
-	_, table := m.CurrentSchema(stmt, stmt.Table)
+	currentSchema, table := m.CurrentSchema(stmt, stmt.Table)
 
 	// DefaultValueValue is reset by ColumnTypes, search again.
 	var columnDefault string
-	err = tx.Raw(
-		`SELECT column_default FROM information_schema.columns WHERE table_name = ? AND column_name = ?`,
-		table, field.DBName).Scan(&columnDefault).Error
+	err = m.queryRaw(
+		`SELECT column_default FROM information_schema.columns WHERE table_schema = ? AND table_name = ? AND column_name = ?`,
+		currentSchema, table, field.DBName).Scan(&columnDefault).Error
 
 	if err != nil {
 		return
@@ -750,6 +1187,10 @@ func (m Migrator) GetIndexes(value interface{}) ([]gorm.Index, error) {
 		if scanErr != nil {
 			return scanErr
 		}
+		elements, err := m.indexElements(stmt)
+		if err != nil {
+			return err
+		}
 		indexMap := groupByIndexName(result)
 		for _, idx := range indexMap {
 			tempIdx := &migrator.Index{
@@ -767,7 +1208,11 @@ func (m Migrator) GetIndexes(value interface{}) ([]gorm.Index, error) {
 			for _, x := range idx {
 				tempIdx.ColumnList = append(tempIdx.ColumnList, x.ColumnName)
 			}
-			indexes = append(indexes, tempIdx)
+			definition := &IndexDefinition{Index: tempIdx}
+			if current, ok := elements[idx[0].IndexName]; ok {
+				definition.Method, definition.Elements = current.Method, current.Elements
+			}
+			indexes = append(indexes, definition)
 		}
 		return nil
 	})
@@ -0,0 +1,243 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+const onlineMigrationKey = "postgres:online_migration"
+
+// lock_not_available, raised when lock_timeout expires
+const lockNotAvailableCode = "55P03"
+
+var (
+	concurrentlyPattern = regexp.MustCompile(`(?i)\bCONCURRENTLY\b`)
+	// concurrentBuildPattern matches the statements building an index concurrently, which leave an invalid index behind on failure
+	concurrentBuildPattern = regexp.MustCompile(`(?is)^\s*(CREATE|REINDEX)\b.*\bCONCURRENTLY\b`)
+)
+
+// OnlineMigration configures the lock-safe migration mode, see WithOnlineMigration
+type OnlineMigration struct {
+	// LockTimeout is set as `lock_timeout` for every DDL statement, zero keeps the server setting
+	LockTimeout time.Duration
+	// StatementTimeout is set as `statement_timeout` for every DDL statement, zero keeps the server setting
+	StatementTimeout time.Duration
+	// Retries is how many times a DDL statement is retried after hitting the lock timeout
+	Retries int
+	// RetryInterval is the pause between two retries
+	RetryInterval time.Duration
+}
+
+// WithOnlineMigration returns a session whose Migrator is safe to run against live traffic:
+//
+//   - every DDL statement runs with `lock_timeout`/`statement_timeout` and is retried when the lock timeout hits
+//   - indexes are built `CONCURRENTLY`
+//   - NOT NULL is added through a `CHECK (...) NOT VALID` constraint that is validated before `SET NOT NULL`
+//   - foreign keys and check constraints are added `NOT VALID` and validated afterwards
+//
+// For example:
+//
+//	postgres.WithOnlineMigration(db, postgres.OnlineMigration{LockTimeout: 2 * time.Second, Retries: 5}).AutoMigrate(&User{})
+func WithOnlineMigration(db *gorm.DB, config OnlineMigration) *gorm.DB {
+	return db.Set(onlineMigrationKey, config)
+}
+
+func onlineMigrationOf(db *gorm.DB) (config OnlineMigration, ok bool) {
+	if v, found := db.Get(onlineMigrationKey); found {
+		config, ok = v.(OnlineMigration)
+	}
+	return
+}
+
+// onlineConnPool applies the online migration timeouts and retries to every statement executed on the pool
+type onlineConnPool struct {
+	gorm.ConnPool
+	config OnlineMigration
+}
+
+// onlineTx is an onlineConnPool wrapping a transaction
+type onlineTx struct {
+	*onlineConnPool
+}
+
+func newOnlineConnPool(pool gorm.ConnPool, config OnlineMigration) gorm.ConnPool {
+	switch pool.(type) {
+	case *onlineConnPool, *onlineTx:
+		return pool
+	}
+
+	online := &onlineConnPool{ConnPool: pool, config: config}
+	if _, ok := pool.(gorm.TxCommitter); ok {
+		return &onlineTx{online}
+	}
+	return online
+}
+
+func (pool *onlineConnPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	tx, err := pool.begin(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &onlineTx{&onlineConnPool{ConnPool: tx, config: pool.config}}, nil
+}
+
+func (pool *onlineConnPool) GetDBConn() (*sql.DB, error) {
+	if db, ok := pool.sqlDB(); ok {
+		return db, nil
+	}
+	return nil, gorm.ErrInvalidDB
+}
+
+func (tx *onlineTx) Commit() error {
+	return tx.ConnPool.(gorm.TxCommitter).Commit()
+}
+
+func (tx *onlineTx) Rollback() error {
+	return tx.ConnPool.(gorm.TxCommitter).Rollback()
+}
+
+// ExecContext executes query with the timeouts, retrying it when the lock timeout hits, except for concurrent index builds as
+// the retry would fail on or keep the invalid index left behind, CreateIndex drops it instead
+func (pool *onlineConnPool) ExecContext(ctx context.Context, query string, args ...interface{}) (result sql.Result, err error) {
+	for retry := 0; ; retry++ {
+		result, err = pool.execContext(ctx, query, args...)
+		if err == nil || retry >= pool.config.Retries || !isLockTimeout(err) || concurrentBuildPattern.MatchString(query) {
+			return result, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(pool.config.RetryInterval):
+		}
+	}
+}
+
+func (pool *onlineConnPool) execContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	timeoutSQL, timeoutVars := pool.timeouts(true)
+	concurrently := concurrentlyPattern.MatchString(query)
+
+	// inside a transaction, scope the statement with a savepoint so it can be retried
+	if _, ok := pool.ConnPool.(gorm.TxCommitter); ok {
+		if concurrently || timeoutSQL == "" {
+			return pool.ConnPool.ExecContext(ctx, query, args...)
+		}
+		if _, err := pool.ConnPool.ExecContext(ctx, "SAVEPOINT online_migration"); err != nil {
+			return nil, err
+		}
+		result, err := pool.execWithTimeouts(ctx, pool.ConnPool, timeoutSQL, timeoutVars, query, args...)
+		if err != nil {
+			pool.ConnPool.ExecContext(ctx, "ROLLBACK TO SAVEPOINT online_migration")
+			return nil, err
+		}
+		_, err = pool.ConnPool.ExecContext(ctx, "RELEASE SAVEPOINT online_migration")
+		return result, err
+	}
+
+	if timeoutSQL == "" {
+		return pool.ConnPool.ExecContext(ctx, query, args...)
+	}
+
+	// CONCURRENTLY can't run inside a transaction, set the timeouts on a pinned connection and reset them afterwards
+	if concurrently {
+		db, ok := pool.sqlDB()
+		if !ok {
+			return pool.ConnPool.ExecContext(ctx, query, args...)
+		}
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+
+		sessionSQL, sessionVars := pool.timeouts(false)
+		result, err := pool.execWithTimeouts(ctx, conn, sessionSQL, sessionVars, query, args...)
+		if _, resetErr := conn.ExecContext(ctx, "RESET lock_timeout"); err == nil {
+			err = resetErr
+		}
+		if _, resetErr := conn.ExecContext(ctx, "RESET statement_timeout"); err == nil {
+			err = resetErr
+		}
+		return result, err
+	}
+
+	tx, err := pool.begin(ctx, nil)
+	if err != nil {
+		if errors.Is(err, gorm.ErrInvalidTransaction) {
+			return pool.ConnPool.ExecContext(ctx, query, args...)
+		}
+		return nil, err
+	}
+
+	result, err := pool.execWithTimeouts(ctx, tx, timeoutSQL, timeoutVars, query, args...)
+	if err != nil {
+		tx.(gorm.TxCommitter).Rollback()
+		return nil, err
+	}
+	return result, tx.(gorm.TxCommitter).Commit()
+}
+
+func (pool *onlineConnPool) execWithTimeouts(ctx context.Context, conn gorm.ConnPool, timeoutSQL string, timeoutVars []interface{}, query string, args ...interface{}) (sql.Result, error) {
+	if _, err := conn.ExecContext(ctx, timeoutSQL, timeoutVars...); err != nil {
+		return nil, err
+	}
+	return conn.ExecContext(ctx, query, args...)
+}
+
+// timeouts returns the statement setting the configured timeouts, empty if none is configured
+func (pool *onlineConnPool) timeouts(local bool) (string, []interface{}) {
+	var (
+		settings []string
+		vars     []interface{}
+	)
+
+	if pool.config.LockTimeout > 0 {
+		settings = append(settings, "lock_timeout")
+		vars = append(vars, strconv.FormatInt(pool.config.LockTimeout.Milliseconds(), 10)+"ms")
+	}
+
+	if pool.config.StatementTimeout > 0 {
+		settings = append(settings, "statement_timeout")
+		vars = append(vars, strconv.FormatInt(pool.config.StatementTimeout.Milliseconds(), 10)+"ms")
+	}
+
+	if len(settings) == 0 {
+		return "", nil
+	}
+
+	query := "SELECT "
+	for idx, setting := range settings {
+		if idx > 0 {
+			query += ", "
+		}
+		query += "set_config('" + setting + "', $" + strconv.Itoa(idx+1) + ", " + strconv.FormatBool(local) + ")"
+	}
+	return query, vars
+}
+
+func (pool *onlineConnPool) begin(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	return beginTx(ctx, pool.ConnPool, opts)
+}
+
+func (pool *onlineConnPool) sqlDB() (*sql.DB, bool) {
+	switch p := pool.ConnPool.(type) {
+	case *sql.DB:
+		return p, true
+	case gorm.GetDBConnector:
+		db, err := p.GetDBConn()
+		return db, err == nil && db != nil
+	}
+	return nil, false
+}
+
+func isLockTimeout(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == lockNotAvailableCode
+}
@@ -0,0 +1,189 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+func Test_onlineConnPool_timeouts(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   OnlineMigration
+		local    bool
+		wantSQL  string
+		wantVars []interface{}
+	}{
+		{
+			name:    "it should return empty sql without timeouts",
+			config:  OnlineMigration{Retries: 3},
+			local:   true,
+			wantSQL: "",
+		},
+		{
+			name:     "it should set lock_timeout locally",
+			config:   OnlineMigration{LockTimeout: 2 * time.Second},
+			local:    true,
+			wantSQL:  "SELECT set_config('lock_timeout', $1, true)",
+			wantVars: []interface{}{"2000ms"},
+		},
+		{
+			name:     "it should set both timeouts for the session",
+			config:   OnlineMigration{LockTimeout: time.Second, StatementTimeout: time.Minute},
+			local:    false,
+			wantSQL:  "SELECT set_config('lock_timeout', $1, false), set_config('statement_timeout', $2, false)",
+			wantVars: []interface{}{"1000ms", "60000ms"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := &onlineConnPool{config: tt.config}
+			gotSQL, gotVars := pool.timeouts(tt.local)
+			if gotSQL != tt.wantSQL {
+				t.Errorf("timeouts() sql = %v, want %v", gotSQL, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(gotVars, tt.wantVars) {
+				t.Errorf("timeouts() vars = %v, want %v", gotVars, tt.wantVars)
+			}
+		})
+	}
+}
+
+func Test_isLockTimeout(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "it should match lock_not_available", err: &pgconn.PgError{Code: "55P03"}, want: true},
+		{name: "it should match wrapped errors", err: fmt.Errorf("migrate: %w", &pgconn.PgError{Code: "55P03"}), want: true},
+		{name: "it should not match other codes", err: &pgconn.PgError{Code: "23505"}, want: false},
+		{name: "it should not match other errors", err: errors.New("55P03"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isLockTimeout(tt.err); got != tt.want {
+				t.Errorf("isLockTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithOnlineMigration(t *testing.T) {
+	db := testDB(t, Config{})
+
+	if _, ok := db.Migrator().(Migrator).DB.Statement.ConnPool.(*onlineConnPool); ok {
+		t.Errorf("expected the default migrator to use the plain connection pool")
+	}
+
+	online := WithOnlineMigration(db, OnlineMigration{LockTimeout: time.Second})
+	pool, ok := online.Migrator().(Migrator).DB.Statement.ConnPool.(*onlineConnPool)
+	if !ok {
+		t.Fatalf("expected the online migrator to use the online connection pool")
+	}
+	if pool.ConnPool != db.ConnPool {
+		t.Errorf("expected the online connection pool to wrap the sql db")
+	}
+	if wrapped := newOnlineConnPool(pool, OnlineMigration{}); wrapped != pool {
+		t.Errorf("expected the online connection pool not to be wrapped twice")
+	}
+}
+
+func TestOnlineConnPool_ExecContext(t *testing.T) {
+	const (
+		alterSQL       = "ALTER TABLE users ADD COLUMN name text"
+		createIndexSQL = "CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_users_name ON users (name)"
+	)
+	var (
+		setTimeout = "SELECT set_config('lock_timeout', $1, true)"
+		lockError  = &pgconn.PgError{Code: "55P03"}
+	)
+
+	tests := []struct {
+		name     string
+		query    string
+		inTx     bool
+		retries  int
+		failures int
+		wantErr  bool
+		want     []string
+	}{
+		{
+			name:     "it should retry in a transaction of its own",
+			retries:  1,
+			failures: 1,
+			want:     []string{"BEGIN", setTimeout, alterSQL, "ROLLBACK", "BEGIN", setTimeout, alterSQL, "COMMIT"},
+		},
+		{
+			name:     "it should retry from a savepoint in a transaction",
+			inTx:     true,
+			retries:  1,
+			failures: 1,
+			want: []string{
+				"BEGIN", "SAVEPOINT online_migration", setTimeout, alterSQL, "ROLLBACK TO SAVEPOINT online_migration",
+				"SAVEPOINT online_migration", setTimeout, alterSQL, "RELEASE SAVEPOINT online_migration",
+			},
+		},
+		{
+			name:     "it should return the lock timeout once the retries are exhausted",
+			inTx:     true,
+			retries:  1,
+			failures: 2,
+			wantErr:  true,
+			want: []string{
+				"BEGIN", "SAVEPOINT online_migration", setTimeout, alterSQL, "ROLLBACK TO SAVEPOINT online_migration",
+				"SAVEPOINT online_migration", setTimeout, alterSQL, "ROLLBACK TO SAVEPOINT online_migration",
+			},
+		},
+		{
+			name:     "it should not retry a concurrent index build",
+			query:    createIndexSQL,
+			retries:  1,
+			failures: 1,
+			wantErr:  true,
+			want:     []string{"SELECT set_config('lock_timeout', $1, false)", createIndexSQL, "RESET lock_timeout", "RESET statement_timeout"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := tt.query
+			if query == "" {
+				query = alterSQL
+			}
+			failures := tt.failures
+			db, connector := stubDB(t, func(statement string) ([]string, [][]driver.Value, error) {
+				if statement == query && failures > 0 {
+					failures--
+					return nil, nil, lockError
+				}
+				return nil, nil, nil
+			})
+
+			var pool gorm.ConnPool = db.ConnPool
+			if tt.inTx {
+				sqlDB, _ := db.DB()
+				tx, err := sqlDB.Begin()
+				if err != nil {
+					t.Fatalf("failed to begin a transaction, got error %v", err)
+				}
+				defer tx.Rollback()
+				pool = tx
+			}
+
+			online := newOnlineConnPool(pool, OnlineMigration{LockTimeout: time.Second, Retries: tt.retries})
+			if _, err := online.ExecContext(context.Background(), query); (err != nil) != tt.wantErr || (err != nil && !isLockTimeout(err)) {
+				t.Errorf("ExecContext() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := connector.statements(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExecContext() executed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
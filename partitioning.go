@@ -0,0 +1,240 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// PartitionStrategy the partitioning method of a table
+type PartitionStrategy string
+
+const (
+	PartitionByRange PartitionStrategy = "RANGE"
+	PartitionByList  PartitionStrategy = "LIST"
+	PartitionByHash  PartitionStrategy = "HASH"
+)
+
+// Partitioning describes how a table is partitioned
+type Partitioning struct {
+	Strategy PartitionStrategy
+	// Columns are the partition key columns
+	Columns []string
+	// Expression is used as the partition key instead of Columns when set, e.g. `date_trunc('day', created_at)`
+	Expression string
+}
+
+// Partitioned a model whose table is created as a partitioned table by the Migrator
+//
+//	func (Event) Partitioning() postgres.Partitioning {
+//		return postgres.Partitioning{Strategy: postgres.PartitionByRange, Columns: []string{"created_at"}}
+//	}
+//
+// the partition key can also be declared with the tag `partition`, e.g. `gorm:"partition:range"`
+type Partitioned interface {
+	Partitioning() Partitioning
+}
+
+// PartitionBound the values of a partition, only the fields of the table partition strategy should be set.
+// Range bounds accept `clause.Expr{SQL: "MINVALUE"}` and `clause.Expr{SQL: "MAXVALUE"}`
+type PartitionBound struct {
+	// From and To are the range bounds, From is inclusive and To exclusive
+	From, To []interface{}
+	// In are the list values
+	In []interface{}
+	// Modulus and Remainder are the hash bounds
+	Modulus, Remainder int
+	// Default creates the default partition
+	Default bool
+}
+
+// Partition a partition of a partitioned table
+type Partition struct {
+	Name  string
+	Bound string
+}
+
+// partitioningOf returns the partitioning of a model, from the Partitioned interface or the `partition` tag
+func partitioningOf(value interface{}, s *schema.Schema) (partitioning Partitioning, ok bool, err error) {
+	if partitioned, isPartitioned := value.(Partitioned); isPartitioned {
+		return partitioned.Partitioning(), true, nil
+	}
+
+	if s == nil {
+		return
+	}
+	for _, field := range s.Fields {
+		strategy, found := field.TagSettings["PARTITION"]
+		if !found || field.DBName == "" {
+			continue
+		}
+
+		strategy = strings.ToUpper(strings.TrimSpace(strategy))
+		if partitioning.Strategy != "" && string(partitioning.Strategy) != strategy {
+			return partitioning, false, fmt.Errorf("conflicting partition strategies %s and %s on %s", partitioning.Strategy, strategy, s.Table)
+		}
+		partitioning.Strategy = PartitionStrategy(strategy)
+		partitioning.Columns = append(partitioning.Columns, field.DBName)
+	}
+	return partitioning, partitioning.Strategy != "", nil
+}
+
+// partitionClause returns the `PARTITION BY` clause of partitioning
+func partitionClause(stmt *gorm.Statement, partitioning Partitioning) (string, error) {
+	switch partitioning.Strategy {
+	case PartitionByRange, PartitionByList, PartitionByHash:
+	default:
+		return "", fmt.Errorf("unsupported partition strategy %s", partitioning.Strategy)
+	}
+
+	key := partitioning.Expression
+	if key == "" {
+		if len(partitioning.Columns) == 0 {
+			return "", fmt.Errorf("partition key of %s is required", stmt.Table)
+		}
+		columns := make([]string, 0, len(partitioning.Columns))
+		for _, column := range partitioning.Columns {
+			columns = append(columns, stmt.Quote(column))
+		}
+		key = strings.Join(columns, ", ")
+	}
+	return fmt.Sprintf(" PARTITION BY %s (%s)", partitioning.Strategy, key), nil
+}
+
+// boundClause returns the `FOR VALUES` clause of bound, values are inlined as DDL doesn't accept bind parameters
+func (m Migrator) boundClause(bound PartitionBound) (string, error) {
+	values := func(values []interface{}) string {
+		literals := make([]string, 0, len(values))
+		for _, value := range values {
+			switch v := value.(type) {
+			case clause.Expr:
+				literals = append(literals, v.SQL)
+			case time.Time:
+				literals = append(literals, "'"+v.Format(time.RFC3339Nano)+"'")
+			default:
+				literals = append(literals, m.Migrator.Dialector.Explain("$1", v))
+			}
+		}
+		return strings.Join(literals, ", ")
+	}
+
+	switch {
+	case bound.Default:
+		return "DEFAULT", nil
+	case len(bound.From) > 0 || len(bound.To) > 0:
+		if len(bound.From) != len(bound.To) {
+			return "", fmt.Errorf("range bound requires as many From as To values")
+		}
+		return fmt.Sprintf("FOR VALUES FROM (%s) TO (%s)", values(bound.From), values(bound.To)), nil
+	case len(bound.In) > 0:
+		return fmt.Sprintf("FOR VALUES IN (%s)", values(bound.In)), nil
+	case bound.Modulus > 0:
+		return fmt.Sprintf("FOR VALUES WITH (MODULUS %d, REMAINDER %d)", bound.Modulus, bound.Remainder), nil
+	}
+	return "", fmt.Errorf("partition bound is required")
+}
+
+// CreatePartition creates the partition name of value's partitioned table
+//
+//	db.Migrator().(postgres.Migrator).CreatePartition(&Event{}, "events_2024_01", postgres.PartitionBound{
+//		From: []interface{}{"2024-01-01"}, To: []interface{}{"2024-02-01"},
+//	})
+func (m Migrator) CreatePartition(value interface{}, name string, bound PartitionBound) error {
+	boundSQL, err := m.boundClause(bound)
+	if err != nil {
+		return err
+	}
+
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		return m.DB.Exec(
+			"CREATE TABLE IF NOT EXISTS ? PARTITION OF ? "+boundSQL, m.qualifiedName(stmt, name), m.CurrentTable(stmt),
+		).Error
+	})
+}
+
+// AttachPartition attaches the existing table name as a partition of value's partitioned table
+func (m Migrator) AttachPartition(value interface{}, name string, bound PartitionBound) error {
+	boundSQL, err := m.boundClause(bound)
+	if err != nil {
+		return err
+	}
+
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		return m.DB.Exec(
+			"ALTER TABLE ? ATTACH PARTITION ? "+boundSQL, m.CurrentTable(stmt), m.qualifiedName(stmt, name),
+		).Error
+	})
+}
+
+// DetachPartition detaches the partition name from value's partitioned table, keeping it as a standalone table
+func (m Migrator) DetachPartition(value interface{}, name string, concurrently bool) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		detachSQL := "ALTER TABLE ? DETACH PARTITION ?"
+		if concurrently {
+			detachSQL += " CONCURRENTLY"
+		}
+		return m.DB.Exec(detachSQL, m.CurrentTable(stmt), m.qualifiedName(stmt, name)).Error
+	})
+}
+
+// DropPartition drops the partition name of value's partitioned table
+func (m Migrator) DropPartition(value interface{}, name string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		return m.DB.Exec("DROP TABLE IF EXISTS ?", m.qualifiedName(stmt, name)).Error
+	})
+}
+
+// CreateMonthlyPartitions creates the monthly range partitions of value's table for the months months starting with the month of from,
+// partitions are named `<table>_<yyyy>_<mm>`, existing partitions are kept, so it can be run periodically to create partitions ahead of time
+//
+//	db.Migrator().(postgres.Migrator).CreateMonthlyPartitions(&Event{}, time.Now(), 3)
+func (m Migrator) CreateMonthlyPartitions(value interface{}, from time.Time, months int) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		_, table := m.CurrentSchema(stmt, stmt.Table)
+		month := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, from.Location())
+		for i := 0; i < months; i++ {
+			next := month.AddDate(0, 1, 0)
+			name := fmt.Sprintf("%s_%04d_%02d", table, month.Year(), int(month.Month()))
+			if err := m.CreatePartition(value, name, PartitionBound{From: []interface{}{month}, To: []interface{}{next}}); err != nil {
+				return err
+			}
+			month = next
+		}
+		return nil
+	})
+}
+
+// GetPartitions returns the partitions of value's partitioned table
+func (m Migrator) GetPartitions(value interface{}) (partitions []Partition, err error) {
+	err = m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		currentSchema, curTable := m.CurrentSchema(stmt, stmt.Table)
+		return m.queryRaw(
+			"SELECT c.relname AS name, pg_get_expr(c.relpartbound, c.oid) AS bound FROM pg_catalog.pg_inherits i "+
+				"JOIN pg_catalog.pg_class c ON c.oid = i.inhrelid JOIN pg_catalog.pg_class p ON p.oid = i.inhparent "+
+				"JOIN pg_catalog.pg_namespace n ON n.oid = p.relnamespace WHERE n.nspname = ? AND p.relname = ? ORDER BY c.relname",
+			currentSchema, curTable,
+		).Scan(&partitions).Error
+	})
+	return
+}
+
+// partitionTableOptions prepends the `PARTITION BY` clause of value to the configured table options
+func partitionTableOptions(db *gorm.DB, stmt *gorm.Statement, value interface{}) (*gorm.DB, error) {
+	partitioning, ok, err := partitioningOf(value, stmt.Schema)
+	if err != nil || !ok {
+		return db, err
+	}
+
+	options, err := partitionClause(stmt, partitioning)
+	if err != nil {
+		return db, err
+	}
+	if tableOption, ok := db.Get("gorm:table_options"); ok {
+		options += " " + strings.TrimSpace(fmt.Sprint(tableOption))
+	}
+	return db.Set("gorm:table_options", options), nil
+}
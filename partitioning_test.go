@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+type partitionedEvent struct {
+	ID        uint64
+	TenantID  uint64    `gorm:"partition:list"`
+	CreatedAt time.Time `gorm:"partition:list"`
+}
+
+type rangePartitionedEvent struct {
+	ID        uint64
+	CreatedAt time.Time
+}
+
+func (rangePartitionedEvent) Partitioning() Partitioning {
+	return Partitioning{Strategy: PartitionByRange, Columns: []string{"created_at"}}
+}
+
+type conflictingPartitionedEvent struct {
+	ID        uint64    `gorm:"partition:hash"`
+	CreatedAt time.Time `gorm:"partition:range"`
+}
+
+func Test_partitionClause(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   interface{}
+		want    string
+		wantErr bool
+	}{
+		{name: "it should partition from the interface", value: &rangePartitionedEvent{}, want: ` PARTITION BY RANGE ("created_at")`},
+		{name: "it should partition from the tags", value: &partitionedEvent{}, want: ` PARTITION BY LIST ("tenant_id", "created_at")`},
+		{name: "it should reject conflicting strategies", value: &conflictingPartitionedEvent{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := schema.Parse(tt.value, &sync.Map{}, schema.NamingStrategy{})
+			if err != nil {
+				t.Fatalf("failed to parse schema, got error %v", err)
+			}
+			stmt := &gorm.Statement{DB: &gorm.DB{Config: &gorm.Config{Dialector: Dialector{Config: &Config{}}}}, Schema: s, Table: s.Table}
+
+			partitioning, ok, err := partitioningOf(tt.value, s)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got partitioning %v", partitioning)
+				}
+				return
+			}
+			if err != nil || !ok {
+				t.Fatalf("expected a partitioning, got error %v", err)
+			}
+
+			got, err := partitionClause(stmt, partitioning)
+			if err != nil {
+				t.Fatalf("failed to build partition clause, got error %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("partitionClause() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrator_boundClause(t *testing.T) {
+	db := testDB(t, Config{})
+	m := db.Migrator().(Migrator)
+
+	tests := []struct {
+		name    string
+		bound   PartitionBound
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "it should inline range bounds",
+			bound: PartitionBound{From: []interface{}{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}, To: []interface{}{time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)}},
+			want:  "FOR VALUES FROM ('2024-01-01T00:00:00Z') TO ('2024-02-01T00:00:00Z')",
+		},
+		{
+			name:  "it should keep range expressions",
+			bound: PartitionBound{From: []interface{}{clause.Expr{SQL: "MINVALUE"}}, To: []interface{}{100}},
+			want:  "FOR VALUES FROM (MINVALUE) TO (100)",
+		},
+		{
+			name:  "it should escape list values",
+			bound: PartitionBound{In: []interface{}{"eu", "it's"}},
+			want:  "FOR VALUES IN ('eu', 'it''s')",
+		},
+		{name: "it should build hash bounds", bound: PartitionBound{Modulus: 4, Remainder: 1}, want: "FOR VALUES WITH (MODULUS 4, REMAINDER 1)"},
+		{name: "it should build the default partition", bound: PartitionBound{Default: true}, want: "DEFAULT"},
+		{name: "it should reject unbalanced ranges", bound: PartitionBound{From: []interface{}{1}}, wantErr: true},
+		{name: "it should reject empty bounds", bound: PartitionBound{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.boundClause(tt.bound)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("boundClause() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("boundClause() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
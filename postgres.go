@@ -32,6 +32,11 @@ type Config struct {
 	WithoutReturning     bool
 	Conn                 gorm.ConnPool
 	OptionOpenDB         []stdlib.OptionOpenDB
+	// SearchPath is the `search_path` of the connections opened from DSN, its first schema is the default schema of the Migrator,
+	// the connections of Conn and DriverName keep their own `search_path`, so their Migrator uses CURRENT_SCHEMA()
+	SearchPath []string
+	// TenantSchema maps a tenant to its schema, see WithTenant, defaults to the tenant itself
+	TenantSchema func(tenant string) string
 }
 
 var (
@@ -78,7 +83,7 @@ func (dialector Dialector) Initialize(db *gorm.DB) (err error) {
 	callbackConfig := &callbacks.Config{
 		CreateClauses: []string{"INSERT", "VALUES", "ON CONFLICT"},
 		UpdateClauses: []string{"UPDATE", "SET", "FROM", "WHERE"},
-		DeleteClauses: []string{"DELETE", "FROM", "WHERE"},
+		DeleteClauses: []string{"DELETE", "FROM", "USING", "WHERE"},
 	}
 	// register callbacks
 	if !dialector.WithoutReturning {
@@ -87,6 +92,15 @@ func (dialector Dialector) Initialize(db *gorm.DB) (err error) {
 		callbackConfig.DeleteClauses = append(callbackConfig.DeleteClauses, "RETURNING")
 	}
 	callbacks.RegisterDefaultCallbacks(db, callbackConfig)
+	if err = registerSettingsCallbacks(db); err != nil {
+		return
+	}
+	if err = registerGeneratedColumnCallbacks(db, !dialector.WithoutReturning); err != nil {
+		return
+	}
+	if err = registerOnConflictCallbacks(db, !dialector.WithoutReturning); err != nil {
+		return
+	}
 
 	if dialector.Conn != nil {
 		db.ConnPool = dialector.Conn
@@ -102,6 +116,9 @@ func (dialector Dialector) Initialize(db *gorm.DB) (err error) {
 		if dialector.Config.PreferSimpleProtocol {
 			config.DefaultQueryExecMode = pgx.QueryExecModeSimpleProtocol
 		}
+		if len(dialector.Config.SearchPath) > 0 {
+			config.RuntimeParams["search_path"] = searchPath(dialector.Config.SearchPath)
+		}
 		result := timeZoneMatcher.FindStringSubmatch(dialector.Config.DSN)
 		if len(result) > 2 {
 			config.RuntimeParams["timezone"] = result[2]
@@ -124,6 +141,12 @@ func (dialector Dialector) Initialize(db *gorm.DB) (err error) {
 }
 
 func (dialector Dialector) Migrator(db *gorm.DB) gorm.Migrator {
+	if config, ok := onlineMigrationOf(db); ok {
+		// clone the statement before replacing its connection pool
+		db = db.Session(&gorm.Session{Context: db.Statement.Context})
+		db.Statement.ConnPool = newOnlineConnPool(db.Statement.ConnPool, config)
+	}
+
 	return Migrator{migrator.Migrator{Config: migrator.Config{
 		DB:                          db,
 		Dialector:                   dialector,
@@ -1,8 +1,15 @@
 package postgres
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
 	"testing"
-"gorm.io/gorm/schema"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
 func Test_DataTypeOf(t *testing.T) {
@@ -13,11 +20,11 @@ func Test_DataTypeOf(t *testing.T) {
 		field *schema.Field
 	}
 	tests := []struct {
-		name string
+		name   string
 		fields fields
-		args args
-		want string
-	} {
+		args   args
+		want   string
+	}{
 		{
 			name: "it should return boolean",
 			args: args{field: &schema.Field{DataType: schema.Bool}},
@@ -54,4 +61,136 @@ func Test_DataTypeOf(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+// testDB opens a database with config, on a pool of localhost unless config has a connection, nothing connects before
+// a statement is executed
+func testDB(t *testing.T, config Config) *gorm.DB {
+	t.Helper()
+	if config.Conn == nil {
+		sqlDB, err := sql.Open("pgx", "host=localhost")
+		if err != nil {
+			t.Fatalf("failed to open sql db, got error %v", err)
+		}
+		config.Conn = sqlDB
+	}
+	db, err := gorm.Open(New(config), &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("failed to open db, got error %v", err)
+	}
+	return db
+}
+
+// dryRunDB returns a dry run session of testDB, building the statements without executing them
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	return testDB(t, Config{}).Session(&gorm.Session{DryRun: true, SkipDefaultTransaction: true})
+}
+
+// stubConnector a connector whose connections record the executed statements and answer them with fc, BEGIN, COMMIT and
+// ROLLBACK are recorded too
+type stubConnector struct {
+	mu      sync.Mutex
+	queries []string
+	fc      func(query string) (columns []string, values [][]driver.Value, err error)
+}
+
+// stubDB opens a database on a stubConnector answering the statements with fc, nil answers them with no rows
+func stubDB(t *testing.T, fc func(query string) (columns []string, values [][]driver.Value, err error)) (*gorm.DB, *stubConnector) {
+	t.Helper()
+	connector := &stubConnector{fc: fc}
+	return testDB(t, Config{Conn: sql.OpenDB(connector)}), connector
+}
+
+func (connector *stubConnector) Connect(context.Context) (driver.Conn, error) {
+	return &stubConn{connector: connector}, nil
+}
+
+func (connector *stubConnector) Driver() driver.Driver {
+	return connector
+}
+
+func (connector *stubConnector) Open(string) (driver.Conn, error) {
+	return &stubConn{connector: connector}, nil
+}
+
+// statements returns the statements executed so far
+func (connector *stubConnector) statements() []string {
+	connector.mu.Lock()
+	defer connector.mu.Unlock()
+	return append([]string(nil), connector.queries...)
+}
+
+func (connector *stubConnector) run(query string) ([]string, [][]driver.Value, error) {
+	connector.mu.Lock()
+	connector.queries = append(connector.queries, query)
+	connector.mu.Unlock()
+	if connector.fc == nil {
+		return nil, nil, nil
+	}
+	return connector.fc(query)
+}
+
+type stubConn struct {
+	connector *stubConnector
+}
+
+func (conn *stubConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+
+func (conn *stubConn) Close() error {
+	return nil
+}
+
+func (conn *stubConn) Begin() (driver.Tx, error) {
+	_, _, err := conn.connector.run("BEGIN")
+	return conn, err
+}
+
+func (conn *stubConn) Commit() error {
+	_, _, err := conn.connector.run("COMMIT")
+	return err
+}
+
+func (conn *stubConn) Rollback() error {
+	_, _, err := conn.connector.run("ROLLBACK")
+	return err
+}
+
+func (conn *stubConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if _, _, err := conn.connector.run(query); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (conn *stubConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	columns, values, err := conn.connector.run(query)
+	if err != nil {
+		return nil, err
+	}
+	return &stubRows{columns: columns, values: values}, nil
+}
+
+type stubRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (rows *stubRows) Columns() []string {
+	return rows.columns
+}
+
+func (rows *stubRows) Close() error {
+	return nil
+}
+
+func (rows *stubRows) Next(dest []driver.Value) error {
+	if len(rows.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, rows.values[0])
+	rows.values = rows.values[1:]
+	return nil
+}
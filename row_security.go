@@ -0,0 +1,292 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PolicyCommand the command a row security policy applies to
+type PolicyCommand string
+
+const (
+	PolicyAll    PolicyCommand = "ALL"
+	PolicySelect PolicyCommand = "SELECT"
+	PolicyInsert PolicyCommand = "INSERT"
+	PolicyUpdate PolicyCommand = "UPDATE"
+	PolicyDelete PolicyCommand = "DELETE"
+)
+
+// Policy a row security policy
+type Policy struct {
+	Name string
+	// Command defaults to ALL
+	Command PolicyCommand
+	// Restrictive policies are combined with AND, permissive ones with OR
+	Restrictive bool
+	// Roles defaults to PUBLIC
+	Roles     []string
+	Using     string
+	WithCheck string
+}
+
+// RowSecurity the row level security of a table
+type RowSecurity struct {
+	Enabled bool
+	// Forced applies the policies to the table owner too
+	Forced   bool
+	Policies []Policy
+}
+
+// RowSecured a model whose table row level security is managed by AutoMigrate,
+// policies of the table not declared by the model are dropped
+//
+//	func (Order) RowSecurity() postgres.RowSecurity {
+//		return postgres.RowSecurity{Enabled: true, Policies: []postgres.Policy{
+//			{Name: "tenant_isolation", Using: "tenant_id = current_setting('app.tenant_id')::bigint"},
+//		}}
+//	}
+type RowSecured interface {
+	RowSecurity() RowSecurity
+}
+
+// EnableRowLevelSecurity enables row level security on value's table, force applies it to the table owner too
+func (m Migrator) EnableRowLevelSecurity(value interface{}, force bool) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if err := m.DB.Exec("ALTER TABLE ? ENABLE ROW LEVEL SECURITY", m.CurrentTable(stmt)).Error; err != nil {
+			return err
+		}
+		forceSQL := "ALTER TABLE ? NO FORCE ROW LEVEL SECURITY"
+		if force {
+			forceSQL = "ALTER TABLE ? FORCE ROW LEVEL SECURITY"
+		}
+		return m.DB.Exec(forceSQL, m.CurrentTable(stmt)).Error
+	})
+}
+
+// DisableRowLevelSecurity disables row level security on value's table, its policies are kept
+func (m Migrator) DisableRowLevelSecurity(value interface{}) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if err := m.DB.Exec("ALTER TABLE ? NO FORCE ROW LEVEL SECURITY", m.CurrentTable(stmt)).Error; err != nil {
+			return err
+		}
+		return m.DB.Exec("ALTER TABLE ? DISABLE ROW LEVEL SECURITY", m.CurrentTable(stmt)).Error
+	})
+}
+
+// CreatePolicy creates policy on value's table
+func (m Migrator) CreatePolicy(value interface{}, policy Policy) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		createSQL := "CREATE POLICY ? ON ?"
+		if policy.Restrictive {
+			createSQL += " AS RESTRICTIVE"
+		}
+		if policy.Command != "" {
+			createSQL += " FOR " + string(policy.Command)
+		}
+		policySQL, values := m.policyClauses(policy)
+		return m.DB.Exec(createSQL+policySQL, append([]interface{}{clause.Column{Name: policy.Name}, m.CurrentTable(stmt)}, values...)...).Error
+	})
+}
+
+// AlterPolicy alters the roles and expressions of policy on value's table, the command and restrictiveness of a policy can't be altered
+func (m Migrator) AlterPolicy(value interface{}, policy Policy) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		policySQL, values := m.policyClauses(policy)
+		return m.DB.Exec("ALTER POLICY ? ON ?"+policySQL, append([]interface{}{clause.Column{Name: policy.Name}, m.CurrentTable(stmt)}, values...)...).Error
+	})
+}
+
+// DropPolicy drops the policy name of value's table
+func (m Migrator) DropPolicy(value interface{}, name string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		return m.DB.Exec("DROP POLICY IF EXISTS ? ON ?", clause.Column{Name: name}, m.CurrentTable(stmt)).Error
+	})
+}
+
+// HasPolicy returns whether value's table has the policy name
+func (m Migrator) HasPolicy(value interface{}, name string) bool {
+	var count int64
+	m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		currentSchema, curTable := m.CurrentSchema(stmt, stmt.Table)
+		return m.queryRaw(
+			"SELECT count(*) FROM pg_catalog.pg_policies WHERE schemaname = ? AND tablename = ? AND policyname = ?", currentSchema, curTable, name,
+		).Scan(&count).Error
+	})
+	return count > 0
+}
+
+// GetPolicies returns the policies of value's table, with the expressions as deparsed by the database
+func (m Migrator) GetPolicies(value interface{}) (policies []Policy, err error) {
+	err = m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		currentSchema, curTable := m.CurrentSchema(stmt, stmt.Table)
+		rows, err := m.queryRaw(
+			"SELECT policyname, permissive, array_to_string(roles, ','), cmd, COALESCE(qual, ''), COALESCE(with_check, '') FROM pg_catalog.pg_policies WHERE schemaname = ? AND tablename = ? ORDER BY policyname",
+			currentSchema, curTable,
+		).Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				policy            Policy
+				permissive, roles string
+			)
+			if err := rows.Scan(&policy.Name, &permissive, &roles, &policy.Command, &policy.Using, &policy.WithCheck); err != nil {
+				return err
+			}
+			policy.Restrictive = permissive == "RESTRICTIVE"
+			policy.Roles = strings.Split(roles, ",")
+			policies = append(policies, policy)
+		}
+		return rows.Err()
+	})
+	return
+}
+
+// rowSecurityOf returns whether row level security is enabled and forced on value's table
+func (m Migrator) rowSecurityOf(value interface{}) (enabled, forced bool, err error) {
+	err = m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		currentSchema, curTable := m.CurrentSchema(stmt, stmt.Table)
+		row := m.queryRaw(
+			"SELECT c.relrowsecurity, c.relforcerowsecurity FROM pg_catalog.pg_class c JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = ? AND c.relname = ?",
+			currentSchema, curTable,
+		).Row()
+		if err := row.Scan(&enabled, &forced); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		return nil
+	})
+	return
+}
+
+// migrateRowSecurity applies the row level security declared by value, with diff only the changes from the database are applied
+func (m Migrator) migrateRowSecurity(value interface{}, diff bool) error {
+	secured, ok := value.(RowSecured)
+	if !ok {
+		return nil
+	}
+	security := secured.RowSecurity()
+
+	var (
+		enabled, forced bool
+		existing        []Policy
+		err             error
+	)
+	if diff {
+		if enabled, forced, err = m.rowSecurityOf(value); err != nil {
+			return err
+		}
+		if existing, err = m.GetPolicies(value); err != nil {
+			return err
+		}
+	}
+
+	if security.Enabled && (!enabled || security.Forced != forced) {
+		if err := m.EnableRowLevelSecurity(value, security.Forced); err != nil {
+			return err
+		}
+	} else if !security.Enabled && enabled {
+		if err := m.DisableRowLevelSecurity(value); err != nil {
+			return err
+		}
+	}
+
+	declared := make(map[string]bool, len(security.Policies))
+	for _, policy := range security.Policies {
+		declared[policy.Name] = true
+
+		var current *Policy
+		for idx := range existing {
+			if existing[idx].Name == policy.Name {
+				current = &existing[idx]
+			}
+		}
+
+		switch {
+		case current == nil:
+			err = m.CreatePolicy(value, policy)
+		case policyCommand(current.Command) != policyCommand(policy.Command) || current.Restrictive != policy.Restrictive:
+			if err = m.DropPolicy(value, policy.Name); err == nil {
+				err = m.CreatePolicy(value, policy)
+			}
+		case !policyRolesEqual(current.Roles, policy.Roles) ||
+			normalizeExpression(current.Using) != normalizeExpression(policy.Using) ||
+			normalizeExpression(current.WithCheck) != normalizeExpression(policy.WithCheck):
+			err = m.AlterPolicy(value, policy)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, policy := range existing {
+		if !declared[policy.Name] {
+			if err := m.DropPolicy(value, policy.Name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// policyClauses returns the `TO`, `USING` and `WITH CHECK` clauses of policy
+func (m Migrator) policyClauses(policy Policy) (sql string, values []interface{}) {
+	if len(policy.Roles) > 0 {
+		roles := make([]string, 0, len(policy.Roles))
+		for _, role := range policy.Roles {
+			switch strings.ToUpper(role) {
+			case "PUBLIC", "CURRENT_USER", "CURRENT_ROLE", "SESSION_USER":
+				roles = append(roles, strings.ToUpper(role))
+			default:
+				roles = append(roles, m.DB.Statement.Quote(role))
+			}
+		}
+		sql += " TO " + strings.Join(roles, ", ")
+	} else {
+		sql += " TO PUBLIC"
+	}
+
+	if policy.Using != "" {
+		sql += " USING (?)"
+		values = append(values, clause.Expr{SQL: policy.Using})
+	}
+	if policy.WithCheck != "" {
+		sql += " WITH CHECK (?)"
+		values = append(values, clause.Expr{SQL: policy.WithCheck})
+	}
+	return
+}
+
+func policyCommand(command PolicyCommand) PolicyCommand {
+	if command == "" {
+		return PolicyAll
+	}
+	return PolicyCommand(strings.ToUpper(string(command)))
+}
+
+func policyRolesEqual(current, declared []string) bool {
+	normalize := func(roles []string) string {
+		normalized := make([]string, 0, len(roles))
+		for _, role := range roles {
+			if strings.EqualFold(role, "public") {
+				role = "public"
+			}
+			if role = strings.Trim(role, `"`); role != "" {
+				normalized = append(normalized, role)
+			}
+		}
+		if len(normalized) == 0 {
+			normalized = append(normalized, "public")
+		}
+		sort.Strings(normalized)
+		return strings.Join(normalized, ",")
+	}
+	return normalize(current) == normalize(declared)
+}
@@ -0,0 +1,25 @@
+package postgres
+
+import "testing"
+
+func Test_policyRolesEqual(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  []string
+		declared []string
+		want     bool
+	}{
+		{name: "it should default to public", current: []string{"public"}, declared: nil, want: true},
+		{name: "it should match the public keyword", current: []string{"public"}, declared: []string{"PUBLIC"}, want: true},
+		{name: "it should ignore order", current: []string{"reader", "writer"}, declared: []string{"writer", "reader"}, want: true},
+		{name: "it should compare role names case sensitively", current: []string{"writer"}, declared: []string{"Writer"}, want: false},
+		{name: "it should detect role changes", current: []string{"reader"}, declared: []string{"writer"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policyRolesEqual(tt.current, tt.declared); got != tt.want {
+				t.Errorf("policyRolesEqual() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
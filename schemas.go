@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type schemaKey struct{}
+
+// WithSearchPath returns a session whose statements run with the `search_path` schemas,
+// the first schema is also the schema the Migrator creates and inspects tables in
+//
+//	postgres.WithSearchPath(db, "tenant_42", "public").AutoMigrate(&Order{})
+func WithSearchPath(db *gorm.DB, schemas ...string) *gorm.DB {
+	return db.WithContext(ContextWithSearchPath(db.Statement.Context, schemas...))
+}
+
+// ContextWithSearchPath returns a context carrying the `search_path` schemas, see WithSearchPath
+func ContextWithSearchPath(ctx context.Context, schemas ...string) context.Context {
+	if len(schemas) == 0 {
+		return ctx
+	}
+
+	ctx = ContextWithSettings(ctx, map[string]string{"search_path": searchPath(schemas)})
+	if !strings.HasPrefix(schemas[0], "$") {
+		ctx = context.WithValue(ctx, schemaKey{}, schemas[0])
+	}
+	return ctx
+}
+
+// SchemaFromContext returns the first schema of the search path carried by ctx
+func SchemaFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	schemaName, _ := ctx.Value(schemaKey{}).(string)
+	return schemaName
+}
+
+// searchPath returns the `search_path` value of schemas
+func searchPath(schemas []string) string {
+	quoted := make([]string, 0, len(schemas))
+	for _, schemaName := range schemas {
+		quoted = append(quoted, `"`+strings.ReplaceAll(schemaName, `"`, `""`)+`"`)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// CurrentTable returns the table of stmt, qualified with the default schema when there is one
+func (m Migrator) CurrentTable(stmt *gorm.Statement) interface{} {
+	if stmt.TableExpr == nil && !strings.Contains(stmt.Table, ".") {
+		if schemaName := m.defaultSchema(); schemaName != "" {
+			return clause.Table{Name: schemaName + "." + stmt.Table}
+		}
+	}
+	return m.Migrator.CurrentTable(stmt)
+}
+
+// CreateSchema creates the schema name if it doesn't exist
+func (m Migrator) CreateSchema(name string) error {
+	return m.DB.Exec("CREATE SCHEMA IF NOT EXISTS ?", clause.Table{Name: name}).Error
+}
+
+// DropSchema drops the schema name if it exists, cascade drops the objects it contains too
+func (m Migrator) DropSchema(name string, cascade bool) error {
+	dropSQL := "DROP SCHEMA IF EXISTS ?"
+	if cascade {
+		dropSQL += " CASCADE"
+	}
+	return m.DB.Exec(dropSQL, clause.Table{Name: name}).Error
+}
+
+// HasSchema returns whether the schema name exists
+func (m Migrator) HasSchema(name string) bool {
+	var count int64
+	m.queryRaw("SELECT count(*) FROM pg_catalog.pg_namespace WHERE nspname = ?", name).Scan(&count)
+	return count > 0
+}
+
+// GetSchemas returns the schemas of the database, without the system ones
+func (m Migrator) GetSchemas() (schemas []string, err error) {
+	return schemas, m.queryRaw(
+		"SELECT nspname FROM pg_catalog.pg_namespace WHERE nspname <> 'information_schema' AND nspname NOT LIKE 'pg\\_%' ORDER BY nspname",
+	).Scan(&schemas).Error
+}
+
+// GetSchemaTables returns the tables of schemas as `schema.table`, the tables of every schema returned by GetSchemas when schemas is empty
+func (m Migrator) GetSchemaTables(schemas ...string) (tables []string, err error) {
+	if len(schemas) == 0 {
+		if schemas, err = m.GetSchemas(); err != nil || len(schemas) == 0 {
+			return
+		}
+	}
+
+	return tables, m.queryRaw(
+		"SELECT n.nspname || '.' || c.relname FROM pg_catalog.pg_class c JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname IN ? AND c.relkind IN ('r', 'p') ORDER BY n.nspname, c.relname",
+		schemas,
+	).Scan(&tables).Error
+}
@@ -0,0 +1,69 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/migrator"
+)
+
+func Test_searchPath(t *testing.T) {
+	if got, want := searchPath([]string{"tenant_42", "$user", `we"ird`}), `"tenant_42", "$user", "we""ird"`; got != want {
+		t.Errorf("searchPath() = %v, want %v", got, want)
+	}
+}
+
+func TestWithSearchPath(t *testing.T) {
+	db := testDB(t, Config{})
+
+	m := db.Migrator().(Migrator)
+	if currentSchema, _ := m.CurrentSchema(&gorm.Statement{}, "users"); !reflect.DeepEqual(currentSchema, clause.Expr{SQL: "CURRENT_SCHEMA()"}) {
+		t.Errorf("expected CURRENT_SCHEMA() by default, got %v", currentSchema)
+	}
+
+	tenant := WithSearchPath(db, "tenant_42", "public")
+	if settings := SettingsFromContext(tenant.Statement.Context); settings["search_path"] != `"tenant_42", "public"` {
+		t.Errorf("expected the search_path setting, got %v", settings)
+	}
+
+	m = tenant.Migrator().(Migrator)
+	if currentSchema, _ := m.CurrentSchema(&gorm.Statement{}, "users"); currentSchema != "tenant_42" {
+		t.Errorf("expected the first schema of the search path, got %v", currentSchema)
+	}
+	if currentSchema, _ := m.CurrentSchema(&gorm.Statement{}, "audit.users"); currentSchema != "audit" {
+		t.Errorf("expected the explicit schema of the table, got %v", currentSchema)
+	}
+	if table := m.CurrentTable(&gorm.Statement{Table: "users"}); table != (clause.Table{Name: "tenant_42.users"}) {
+		t.Errorf("expected the table to be qualified, got %v", table)
+	}
+
+	if schemaName := SchemaFromContext(WithSearchPath(db, "$user", "public").Statement.Context); schemaName != "" {
+		t.Errorf("expected no default schema for $user, got %v", schemaName)
+	}
+}
+
+func TestMigrator_defaultSchema(t *testing.T) {
+	db := testDB(t, Config{})
+
+	tests := []struct {
+		name   string
+		config *Config
+		want   string
+	}{
+		{name: "it should use the search path of the connections opened from DSN", config: &Config{DSN: "host=localhost", SearchPath: []string{"app", "public"}}, want: "app"},
+		{name: "it should ignore the search path of a connection passed in", config: &Config{Conn: db.ConnPool, SearchPath: []string{"app", "public"}}},
+		{name: "it should ignore the search path of a driver", config: &Config{DriverName: "pgx", SearchPath: []string{"app", "public"}}},
+		{name: "it should leave $user to the database", config: &Config{DSN: "host=localhost", SearchPath: []string{"$user", "public"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dialector := Dialector{Config: tt.config}
+			m := Migrator{migrator.Migrator{Config: migrator.Config{DB: db, Dialector: dialector}}}
+			if got := m.defaultSchema(); got != tt.want {
+				t.Errorf("defaultSchema() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+const (
+	settingsStartedTransactionKey = "postgres:settings_started_transaction"
+	settingsConnPoolKey           = "postgres:settings_conn_pool"
+)
+
+type settingsKey struct{}
+
+// ContextWithSettings returns a context carrying the run-time settings, e.g. `app.tenant_id`,
+// merged with the settings already carried by ctx
+func ContextWithSettings(ctx context.Context, settings map[string]string) context.Context {
+	merged := make(map[string]string, len(settings))
+	for name, value := range SettingsFromContext(ctx) {
+		merged[name] = value
+	}
+	for name, value := range settings {
+		merged[name] = value
+	}
+	return context.WithValue(ctx, settingsKey{}, merged)
+}
+
+// SettingsFromContext returns the run-time settings carried by ctx
+func SettingsFromContext(ctx context.Context) map[string]string {
+	if ctx == nil {
+		return nil
+	}
+	settings, _ := ctx.Value(settingsKey{}).(map[string]string)
+	return settings
+}
+
+// WithSettings returns a session applying the run-time settings to every statement with `set_config(name, value, true)`,
+// in the current transaction or in a transaction wrapping the single statement, so the settings never leak to other users of the pool,
+// outside of a transaction the rows of Row, Rows and Scan are read into memory before the transaction ends
+//
+//	postgres.WithSettings(db, map[string]string{"app.tenant_id": "42"}).Find(&orders)
+func WithSettings(db *gorm.DB, settings map[string]string) *gorm.DB {
+	return db.WithContext(ContextWithSettings(db.Statement.Context, settings))
+}
+
+// contextWithoutSettings returns ctx without the run-time settings and the tenant, e.g. for catalog queries
+func contextWithoutSettings(ctx context.Context) context.Context {
+	if len(SettingsFromContext(ctx)) > 0 {
+		ctx = context.WithValue(ctx, settingsKey{}, map[string]string(nil))
+	}
+	if TenantFromContext(ctx) != "" {
+		ctx = context.WithValue(ctx, tenantKey{}, "")
+	}
+	return ctx
+}
+
+func registerSettingsCallbacks(db *gorm.DB) error {
+	callback := db.Callback()
+	for _, err := range []error{
+		callback.Create().After("gorm:begin_transaction").Before("gorm:before_create").Register("postgres:apply_settings", applySettings),
+		callback.Update().After("gorm:begin_transaction").Before("gorm:before_update").Register("postgres:apply_settings", applySettings),
+		callback.Delete().After("gorm:begin_transaction").Before("gorm:before_delete").Register("postgres:apply_settings", applySettings),
+		callback.Query().Before("gorm:query").Register("postgres:apply_settings", applySettings),
+		callback.Raw().Before("gorm:raw").Register("postgres:apply_settings", applySettings),
+		callback.Row().Before("gorm:row").Register("postgres:apply_settings", applyRowSettings),
+		callback.Row().After("*").Register("postgres:restore_settings", restoreRowSettings),
+		callback.Create().After("*").Register("postgres:commit_settings", commitSettings),
+		callback.Update().After("*").Register("postgres:commit_settings", commitSettings),
+		callback.Delete().After("*").Register("postgres:commit_settings", commitSettings),
+		callback.Query().After("*").Register("postgres:commit_settings", commitSettings),
+		callback.Raw().After("*").Register("postgres:commit_settings", commitSettings),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applySettings applies the settings of the statement context, beginning a transaction if there is none
+func applySettings(db *gorm.DB) {
+	settings := statementSettings(db)
+	if db.Error != nil || db.DryRun || len(settings) == 0 {
+		return
+	}
+
+	if _, ok := db.Statement.ConnPool.(gorm.TxCommitter); !ok {
+		// e.g. `CREATE INDEX CONCURRENTLY` can't run inside a transaction block
+		if nonTransactionalPattern.MatchString(db.Statement.SQL.String()) {
+			return
+		}
+
+		tx := db.Begin()
+		if tx.Error != nil {
+			db.AddError(tx.Error)
+			return
+		}
+		db.InstanceSet(settingsStartedTransactionKey, db.Statement.ConnPool)
+		db.Statement.ConnPool = tx.Statement.ConnPool
+	}
+
+	query, vars := settingsSQL(settings)
+	if _, err := db.Statement.ConnPool.ExecContext(db.Statement.Context, query, vars...); err != nil {
+		db.AddError(err)
+	}
+}
+
+// applyRowSettings applies the settings of the statement context to Row and Rows, e.g. of Scan, outside of a transaction the
+// query runs on a settingsConnPool
+func applyRowSettings(db *gorm.DB) {
+	settings := statementSettings(db)
+	if db.Error != nil || db.DryRun || len(settings) == 0 {
+		return
+	}
+	if _, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok {
+		applySettings(db)
+		return
+	}
+	db.InstanceSet(settingsConnPoolKey, db.Statement.ConnPool)
+	db.Statement.ConnPool = &settingsConnPool{ConnPool: db.Statement.ConnPool, settings: settings}
+}
+
+// restoreRowSettings restores the connection pool replaced by applyRowSettings
+func restoreRowSettings(db *gorm.DB) {
+	if pool, ok := db.InstanceGet(settingsConnPoolKey); ok {
+		db.Statement.ConnPool = pool.(gorm.ConnPool)
+	}
+}
+
+// commitSettings ends the transaction begun by applySettings
+func commitSettings(db *gorm.DB) {
+	if pool, ok := db.InstanceGet(settingsStartedTransactionKey); ok {
+		if db.Error != nil {
+			db.Rollback()
+		} else {
+			db.Commit()
+		}
+		db.Statement.ConnPool = pool.(gorm.ConnPool)
+	}
+}
+
+// settingsSQL returns the statement applying settings to the current transaction
+func settingsSQL(settings map[string]string) (string, []interface{}) {
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	query := "SELECT "
+	vars := make([]interface{}, 0, len(settings)*2)
+	for idx, name := range names {
+		if idx > 0 {
+			query += ", "
+		}
+		query += "set_config($" + strconv.Itoa(idx*2+1) + ", $" + strconv.Itoa(idx*2+2) + ", true)"
+		vars = append(vars, name, settings[name])
+	}
+	return query, vars
+}
+
+// settingsConnPool runs the queries of Row and Rows in a transaction of their own applying settings, the rows are read before
+// the transaction ends and returned from memory, as the transaction can't be ended when the caller closes them
+type settingsConnPool struct {
+	gorm.ConnPool
+	settings map[string]string
+}
+
+func (pool *settingsConnPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := pool.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	db := sql.OpenDB(rows)
+	defer db.Close()
+	return db.QueryContext(ctx, query)
+}
+
+func (pool *settingsConnPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	rows, err := pool.query(ctx, query, args...)
+	if err != nil {
+		rows = &bufferedRows{err: err}
+	}
+	db := sql.OpenDB(rows)
+	defer db.Close()
+	return db.QueryRowContext(ctx, query)
+}
+
+// query reads the rows of query in a transaction applying the settings
+func (pool *settingsConnPool) query(ctx context.Context, query string, args ...interface{}) (buffered *bufferedRows, err error) {
+	tx, err := beginTx(ctx, pool.ConnPool, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.(gorm.TxCommitter).Rollback()
+		}
+	}()
+
+	settingsQuery, settingsVars := settingsSQL(pool.settings)
+	if _, err = tx.ExecContext(ctx, settingsQuery, settingsVars...); err != nil {
+		return nil, err
+	}
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if buffered, err = readRows(rows); err != nil {
+		return nil, err
+	}
+	return buffered, tx.(gorm.TxCommitter).Commit()
+}
+
+// beginTx begins a transaction on pool
+func beginTx(ctx context.Context, pool gorm.ConnPool, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	switch beginner := pool.(type) {
+	case gorm.TxBeginner:
+		return beginner.BeginTx(ctx, opts)
+	case gorm.ConnPoolBeginner:
+		return beginner.BeginTx(ctx, opts)
+	}
+	return nil, gorm.ErrInvalidTransaction
+}
+
+// readRows reads and closes rows
+func readRows(rows *sql.Rows) (*bufferedRows, error) {
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	buffered := &bufferedRows{}
+	for _, columnType := range columnTypes {
+		buffered.columns = append(buffered.columns, columnType.Name())
+		buffered.scanTypes = append(buffered.scanTypes, columnType.ScanType())
+		buffered.databaseTypes = append(buffered.databaseTypes, columnType.DatabaseTypeName())
+	}
+	for rows.Next() {
+		values := make([]driver.Value, len(columnTypes))
+		dest := make([]interface{}, len(columnTypes))
+		for idx := range values {
+			dest[idx] = &values[idx]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		buffered.values = append(buffered.values, values)
+	}
+	return buffered, rows.Err()
+}
+
+// bufferedRows rows read into memory, returned as sql.Rows by a connector of its own
+type bufferedRows struct {
+	columns       []string
+	scanTypes     []reflect.Type
+	databaseTypes []string
+	values        [][]driver.Value
+	err           error
+}
+
+func (rows *bufferedRows) Connect(context.Context) (driver.Conn, error) {
+	return bufferedConn{rows: rows}, nil
+}
+
+func (rows *bufferedRows) Driver() driver.Driver {
+	return rows
+}
+
+func (rows *bufferedRows) Open(string) (driver.Conn, error) {
+	return bufferedConn{rows: rows}, nil
+}
+
+type bufferedConn struct {
+	rows *bufferedRows
+}
+
+func (conn bufferedConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	if conn.rows.err != nil {
+		return nil, conn.rows.err
+	}
+	rows := *conn.rows
+	return &rows, nil
+}
+
+func (conn bufferedConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("postgres: buffered rows can't be prepared")
+}
+
+func (conn bufferedConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("postgres: buffered rows can't begin a transaction")
+}
+
+func (conn bufferedConn) Close() error {
+	return nil
+}
+
+func (rows *bufferedRows) Columns() []string {
+	return rows.columns
+}
+
+func (rows *bufferedRows) ColumnTypeScanType(index int) reflect.Type {
+	return rows.scanTypes[index]
+}
+
+func (rows *bufferedRows) ColumnTypeDatabaseTypeName(index int) string {
+	return rows.databaseTypes[index]
+}
+
+func (rows *bufferedRows) Close() error {
+	return nil
+}
+
+func (rows *bufferedRows) Next(dest []driver.Value) error {
+	if len(rows.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, rows.values[0])
+	rows.values = rows.values[1:]
+	return nil
+}
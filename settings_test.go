@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func Test_settingsSQL(t *testing.T) {
+	query, vars := settingsSQL(map[string]string{"app.user_id": "7", "app.tenant_id": "42"})
+	if want := "SELECT set_config($1, $2, true), set_config($3, $4, true)"; query != want {
+		t.Errorf("settingsSQL() sql = %v, want %v", query, want)
+	}
+	if want := []interface{}{"app.tenant_id", "42", "app.user_id", "7"}; !reflect.DeepEqual(vars, want) {
+		t.Errorf("settingsSQL() vars = %v, want %v", vars, want)
+	}
+}
+
+func TestContextWithSettings(t *testing.T) {
+	ctx := ContextWithSettings(context.Background(), map[string]string{"app.tenant_id": "42", "app.user_id": "7"})
+	ctx = ContextWithSettings(ctx, map[string]string{"app.user_id": "8"})
+
+	if want := map[string]string{"app.tenant_id": "42", "app.user_id": "8"}; !reflect.DeepEqual(SettingsFromContext(ctx), want) {
+		t.Errorf("expected settings %v, got %v", want, SettingsFromContext(ctx))
+	}
+	if settings := SettingsFromContext(contextWithoutSettings(ctx)); len(settings) != 0 {
+		t.Errorf("expected no settings, got %v", settings)
+	}
+}
+
+func TestWithSettings(t *testing.T) {
+	db := testDB(t, Config{})
+
+	tx := WithSettings(db, map[string]string{"app.tenant_id": "42"})
+	if err := tx.Session(&gorm.Session{DryRun: true}).Exec("DELETE FROM orders").Error; err != nil {
+		t.Errorf("expected settings to be skipped in dry run mode, got error %v", err)
+	}
+}
+
+func TestWithSettings_scan(t *testing.T) {
+	db, connector := stubDB(t, func(query string) ([]string, [][]driver.Value, error) {
+		if query == "SELECT name FROM orders" {
+			return []string{"name"}, [][]driver.Value{{"first"}, {"second"}}, nil
+		}
+		return nil, nil, nil
+	})
+	tx := WithSettings(db, map[string]string{"app.tenant_id": "42"})
+
+	var names []string
+	if err := tx.Raw("SELECT name FROM orders").Scan(&names).Error; err != nil {
+		t.Fatalf("failed to scan with settings, got error %v", err)
+	}
+	if want := []string{"first", "second"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("expected names %v, got %v", want, names)
+	}
+
+	var name string
+	if err := tx.Raw("SELECT name FROM orders").Row().Scan(&name); err != nil || name != "first" {
+		t.Errorf("expected the first name, got %v with error %v", name, err)
+	}
+
+	statements := []string{"BEGIN", "SELECT set_config($1, $2, true)", "SELECT name FROM orders", "COMMIT"}
+	if want := append(statements, statements...); !reflect.DeepEqual(connector.statements(), want) {
+		t.Errorf("expected statements %q, got %q", want, connector.statements())
+	}
+
+	failing, _ := stubDB(t, func(query string) ([]string, [][]driver.Value, error) {
+		if query == "SELECT name FROM orders" {
+			return nil, nil, errors.New("permission denied")
+		}
+		return nil, nil, nil
+	})
+	if err := WithSettings(failing, map[string]string{"app.tenant_id": "42"}).Raw("SELECT name FROM orders").Scan(&names).Error; err == nil {
+		t.Errorf("expected the error of the query")
+	}
+}
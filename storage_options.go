@@ -0,0 +1,235 @@
+package postgres
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// tableStorageSQL selects the storage parameters and the tablespace of a table, the parameters of its TOAST table prefixed
+// with `toast.`
+const tableStorageSQL = `
+SELECT
+	concat_ws(',',
+		array_to_string(c.reloptions, ','),
+		(SELECT array_to_string(ARRAY(SELECT 'toast.' || o FROM unnest(tc.reloptions) AS o), ',') FROM pg_catalog.pg_class tc WHERE tc.oid = c.reltoastrelid)
+	) AS storage_parameters,
+	coalesce(ts.spcname, '') AS tablespace
+FROM
+	pg_catalog.pg_class c
+	JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+	LEFT JOIN pg_catalog.pg_tablespace ts ON ts.oid = c.reltablespace
+WHERE
+	n.nspname = ? AND c.relname = ?
+`
+
+// TableOptions the storage options of a table
+type TableOptions struct {
+	// With the storage parameters, e.g. `fillfactor` or `autovacuum_vacuum_scale_factor`, prefixed with `toast.` for
+	// the TOAST table
+	With map[string]string
+	// Tablespace the tablespace of the table, the default tablespace of the database when empty
+	Tablespace string
+}
+
+// TableOptioned a model whose table is created with its storage options, AutoMigrate sets the changed storage parameters
+// and resets the ones not declared anymore, the table is moved when its declared tablespace changed
+//
+//	func (Event) TableOptions() postgres.TableOptions {
+//		return postgres.TableOptions{With: map[string]string{"fillfactor": "70", "autovacuum_vacuum_scale_factor": "0.01"}}
+//	}
+type TableOptioned interface {
+	TableOptions() TableOptions
+}
+
+// IndexOptions the options of an index
+type IndexOptions struct {
+	// With the storage parameters, e.g. `fillfactor` or `fastupdate`
+	With map[string]string
+	// Tablespace the tablespace of the index, the default tablespace of the database when empty
+	Tablespace string
+	// Include the non-key columns stored in the index, for index-only scans
+	Include []string
+	// NullsNotDistinct makes a unique index reject duplicated nulls
+	NullsNotDistinct bool
+}
+
+// IndexOptioned a model whose indexes are created with their options by index name, AutoMigrate sets the changed storage
+// parameters and tablespaces, and recreates the indexes whose included columns or nulls distinctness changed
+//
+//	func (Event) IndexOptions() map[string]postgres.IndexOptions {
+//		return map[string]postgres.IndexOptions{"idx_events_user_id": {Include: []string{"created_at"}, With: map[string]string{"fillfactor": "90"}}}
+//	}
+type IndexOptioned interface {
+	IndexOptions() map[string]IndexOptions
+}
+
+// tableOptionsOf returns the table options declared by the model of stmt
+func tableOptionsOf(stmt *gorm.Statement) (TableOptions, bool) {
+	if stmt.Schema == nil {
+		return TableOptions{}, false
+	}
+	if optioned, ok := reflect.New(stmt.Schema.ModelType).Interface().(TableOptioned); ok {
+		return optioned.TableOptions(), true
+	}
+	return TableOptions{}, false
+}
+
+// indexOptionsOf returns the options of the index name declared by the model of stmt
+func indexOptionsOf(stmt *gorm.Statement, name string) (IndexOptions, bool) {
+	if stmt.Schema == nil {
+		return IndexOptions{}, false
+	}
+	if optioned, ok := reflect.New(stmt.Schema.ModelType).Interface().(IndexOptioned); ok {
+		options, ok := optioned.IndexOptions()[name]
+		return options, ok
+	}
+	return IndexOptions{}, false
+}
+
+// clauses returns the `INCLUDE`, `NULLS NOT DISTINCT`, `WITH` and `TABLESPACE` clauses of an index
+func (options IndexOptions) clauses(stmt *gorm.Statement) (sql string) {
+	if len(options.Include) > 0 {
+		columns := make([]string, 0, len(options.Include))
+		for _, column := range options.Include {
+			columns = append(columns, stmt.Quote(column))
+		}
+		sql += " INCLUDE (" + strings.Join(columns, ", ") + ")"
+	}
+	if options.NullsNotDistinct {
+		sql += " NULLS NOT DISTINCT"
+	}
+	return sql + storageClauses(stmt, options.With, options.Tablespace)
+}
+
+// storageClauses returns the `WITH` and `TABLESPACE` clauses of a table or an index
+func storageClauses(stmt *gorm.Statement, parameters map[string]string, tablespace string) (sql string) {
+	if len(parameters) > 0 {
+		sql += " WITH (" + storageParametersList(parameters) + ")"
+	}
+	if tablespace != "" {
+		sql += " TABLESPACE " + stmt.Quote(tablespace)
+	}
+	return
+}
+
+// storageParametersList returns parameters as a sorted `name=value` list, values are inlined as DDL doesn't accept
+// bind parameters
+func storageParametersList(parameters map[string]string) string {
+	names := make([]string, 0, len(parameters))
+	for name := range parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	list := make([]string, 0, len(names))
+	for _, name := range names {
+		list = append(list, name+"="+parameters[name])
+	}
+	return strings.Join(list, ", ")
+}
+
+// parseStorageParameters parses the `name=value` storage parameters of a relation as listed by reloptions
+func parseStorageParameters(list string) map[string]string {
+	parameters := map[string]string{}
+	for _, parameter := range strings.Split(list, ",") {
+		if name, value, ok := strings.Cut(parameter, "="); ok {
+			parameters[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+		}
+	}
+	return parameters
+}
+
+// storageParametersDiff returns the parameters to set and the ones to reset so current becomes declared
+func storageParametersDiff(declared, current map[string]string) (set map[string]string, reset []string) {
+	set = map[string]string{}
+	for name, value := range declared {
+		if currentValue, ok := current[strings.ToLower(name)]; !ok || !strings.EqualFold(strings.Trim(currentValue, "'"), strings.Trim(value, "'")) {
+			set[name] = value
+		}
+	}
+	for name := range current {
+		found := false
+		for declaredName := range declared {
+			found = found || strings.EqualFold(declaredName, name)
+		}
+		if !found {
+			reset = append(reset, name)
+		}
+	}
+	sort.Strings(reset)
+	return
+}
+
+// tableStorageOptions appends the storage options declared by value to the configured table options
+func tableStorageOptions(db *gorm.DB, stmt *gorm.Statement) *gorm.DB {
+	options, ok := tableOptionsOf(stmt)
+	if !ok {
+		return db
+	}
+	clauses := storageClauses(stmt, options.With, options.Tablespace)
+	if clauses == "" {
+		return db
+	}
+	if tableOption, ok := db.Get("gorm:table_options"); ok {
+		clauses = " " + strings.TrimSpace(fmt.Sprint(tableOption)) + clauses
+	}
+	return db.Set("gorm:table_options", clauses)
+}
+
+// alterStorage sets the storage parameters and the tablespace of the table or index target to the declared ones
+func (m Migrator) alterStorage(kind string, target interface{}, declared, current map[string]string, tablespace, currentTablespace string) error {
+	set, reset := storageParametersDiff(declared, current)
+	if len(set) > 0 {
+		if err := m.DB.Exec("ALTER "+kind+" ? SET ("+storageParametersList(set)+")", target).Error; err != nil {
+			return err
+		}
+	}
+	if len(reset) > 0 {
+		if err := m.DB.Exec("ALTER "+kind+" ? RESET ("+strings.Join(reset, ", ")+")", target).Error; err != nil {
+			return err
+		}
+	}
+	if tablespace != "" && tablespace != currentTablespace {
+		return m.DB.Exec("ALTER "+kind+" ? SET TABLESPACE ?", target, clause.Column{Name: tablespace}).Error
+	}
+	return nil
+}
+
+// migrateTableOptions migrates the storage parameters and the tablespace of value's table to the declared ones
+func (m Migrator) migrateTableOptions(value interface{}) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		options, ok := tableOptionsOf(stmt)
+		if !ok {
+			return nil
+		}
+
+		var current struct {
+			StorageParameters string
+			Tablespace        string
+		}
+		currentSchema, curTable := m.CurrentSchema(stmt, stmt.Table)
+		if err := m.queryRaw(tableStorageSQL, currentSchema, curTable).Scan(&current).Error; err != nil {
+			return err
+		}
+		return m.alterStorage("TABLE", m.CurrentTable(stmt), options.With, parseStorageParameters(current.StorageParameters), options.Tablespace, current.Tablespace)
+	})
+}
+
+// indexOptionsChanged returns whether the included columns or the nulls distinctness of the index current differ from options,
+// the index has to be recreated then
+func indexOptionsChanged(options IndexOptions, current *IndexDefinition) bool {
+	if options.NullsNotDistinct != current.Options.NullsNotDistinct || len(options.Include) != len(current.Options.Include) {
+		return true
+	}
+	for i, column := range options.Include {
+		if column != strings.Trim(current.Options.Include[i], `"`) {
+			return true
+		}
+	}
+	return false
+}
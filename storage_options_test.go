@@ -0,0 +1,158 @@
+package postgres
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+type storedEvent struct {
+	ID     uint64
+	UserID uint64 `gorm:"index"`
+}
+
+func (storedEvent) TableOptions() TableOptions {
+	return TableOptions{With: map[string]string{"fillfactor": "70", "autovacuum_enabled": "false"}, Tablespace: "fast"}
+}
+
+func (storedEvent) IndexOptions() map[string]IndexOptions {
+	return map[string]IndexOptions{"idx_stored_events_user_id": {Include: []string{"id"}, NullsNotDistinct: true, With: map[string]string{"fillfactor": "90"}}}
+}
+
+func storedEventStatement(t *testing.T) *gorm.Statement {
+	db := testDB(t, Config{})
+	s, err := schema.Parse(&storedEvent{}, &sync.Map{}, db.NamingStrategy)
+	if err != nil {
+		t.Fatalf("failed to parse schema, got error %v", err)
+	}
+	return &gorm.Statement{DB: db, Schema: s, Table: s.Table}
+}
+
+func TestStorageOptionsClauses(t *testing.T) {
+	stmt := storedEventStatement(t)
+
+	options, ok := indexOptionsOf(stmt, "idx_stored_events_user_id")
+	if !ok {
+		t.Fatalf("failed to find the index options")
+	}
+	if got, want := options.clauses(stmt), ` INCLUDE ("id") NULLS NOT DISTINCT WITH (fillfactor=90)`; got != want {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if _, ok := indexOptionsOf(stmt, "idx_stored_events_id"); ok {
+		t.Errorf("expected no options for an index not declared")
+	}
+
+	db := tableStorageOptions(stmt.DB.Set("gorm:table_options", "USING heap"), stmt)
+	if got, _ := db.Get("gorm:table_options"); got != ` USING heap WITH (autovacuum_enabled=false, fillfactor=70) TABLESPACE "fast"` {
+		t.Errorf("expected the storage options after the table options, got %v", got)
+	}
+}
+
+func Test_storageParametersDiff(t *testing.T) {
+	tests := []struct {
+		name      string
+		declared  map[string]string
+		current   string
+		wantSet   map[string]string
+		wantReset []string
+	}{
+		{
+			name:     "it should keep the same parameters",
+			declared: map[string]string{"fillfactor": "70", "autovacuum_enabled": "FALSE"},
+			current:  "fillfactor=70,autovacuum_enabled=false",
+			wantSet:  map[string]string{},
+		},
+		{
+			name:     "it should set the changed and the new parameters",
+			declared: map[string]string{"fillfactor": "80", "toast.autovacuum_enabled": "false"},
+			current:  "fillfactor=70",
+			wantSet:  map[string]string{"fillfactor": "80", "toast.autovacuum_enabled": "false"},
+		},
+		{
+			name:      "it should reset the parameters not declared anymore",
+			declared:  map[string]string{"fillfactor": "70"},
+			current:   "fillfactor=70,autovacuum_vacuum_scale_factor=0.01,toast.autovacuum_enabled=false",
+			wantSet:   map[string]string{},
+			wantReset: []string{"autovacuum_vacuum_scale_factor", "toast.autovacuum_enabled"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, reset := storageParametersDiff(tt.declared, parseStorageParameters(tt.current))
+			if !reflect.DeepEqual(set, tt.wantSet) || !reflect.DeepEqual(reset, tt.wantReset) {
+				t.Errorf("storageParametersDiff() = %v, %v, want %v, %v", set, reset, tt.wantSet, tt.wantReset)
+			}
+		})
+	}
+}
+
+func Test_indexOptionsChanged(t *testing.T) {
+	tests := []struct {
+		name    string
+		options IndexOptions
+		current IndexOptions
+		want    bool
+	}{
+		{name: "it should keep an index with the same included columns", options: IndexOptions{Include: []string{"CreatedAt"}}, current: IndexOptions{Include: []string{`"CreatedAt"`}}},
+		{name: "it should recreate an index with other included columns", options: IndexOptions{Include: []string{"created_at"}}, current: IndexOptions{Include: []string{"id"}}, want: true},
+		{name: "it should recreate an index with nulls not distinct", options: IndexOptions{NullsNotDistinct: true}, want: true},
+		{name: "it should not recreate an index with other storage parameters", options: IndexOptions{With: map[string]string{"fillfactor": "90"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := indexOptionsChanged(tt.options, &IndexDefinition{Options: tt.current}); got != tt.want {
+				t.Errorf("indexOptionsChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrator_migrateIndex_storage(t *testing.T) {
+	current := &IndexDefinition{
+		Method:   "btree",
+		Elements: []IndexElement{{Expression: "user_id", Opclass: "int8_ops", defaultOpclass: true}},
+		Options:  IndexOptions{Include: []string{"id"}, NullsNotDistinct: true, With: map[string]string{"fillfactor": "80", "deduplicate_items": "off"}, Tablespace: "slow"},
+	}
+
+	tests := []struct {
+		name  string
+		table string
+		want  []string
+	}{
+		{
+			name: "it should alter the storage options of the index",
+			want: []string{
+				`ALTER INDEX "idx_stored_events_user_id" SET (fillfactor=90)`,
+				`ALTER INDEX "idx_stored_events_user_id" RESET (deduplicate_items)`,
+			},
+		},
+		{
+			name:  "it should qualify the altered index with the schema of the table",
+			table: "app.stored_events",
+			want: []string{
+				`ALTER INDEX "app"."idx_stored_events_user_id" SET (fillfactor=90)`,
+				`ALTER INDEX "app"."idx_stored_events_user_id" RESET (deduplicate_items)`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &planRecorder{Interface: logger.Discard, plan: &migrationPlan{}}
+			db := dryRunDB(t).Session(&gorm.Session{Logger: recorder})
+			if tt.table != "" {
+				db = db.Table(tt.table)
+			}
+			stmt := storedEventStatement(t)
+			if err := db.Migrator().(Migrator).migrateIndex(&storedEvent{}, stmt.Schema.LookIndex("idx_stored_events_user_id"), current); err != nil {
+				t.Fatalf("migrateIndex() error = %v", err)
+			}
+			if got := recorder.plan.sql; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("migrateIndex() executed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
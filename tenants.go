@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+const defaultTenantConcurrency = 4
+
+type tenantKey struct{}
+
+// TenantMigration configures MigrateTenants
+type TenantMigration struct {
+	Tenants []string
+	// Concurrency is how many tenants are migrated at the same time, defaults to 4
+	Concurrency int
+	// Progress is called after each tenant is migrated, with the error of its migration
+	Progress func(tenant string, done, total int, err error)
+}
+
+// WithTenant returns a session whose statements run in the schema of tenant, see Config.TenantSchema
+//
+//	postgres.WithTenant(db, "acme").Find(&orders)
+func WithTenant(db *gorm.DB, tenant string) *gorm.DB {
+	return db.WithContext(ContextWithTenant(db.Statement.Context, tenant))
+}
+
+// ContextWithTenant returns a context carrying tenant, statements run with it use the tenant schema
+// as the first schema of their `search_path`, unless the context carries a search path already
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant carried by ctx
+func TenantFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// tenantSchema returns the schema of tenant
+func (dialector Dialector) tenantSchema(tenant string) string {
+	if dialector.Config != nil && dialector.Config.TenantSchema != nil {
+		return dialector.Config.TenantSchema(tenant)
+	}
+	return tenant
+}
+
+// tenantSearchPath returns the `search_path` of tenant, its schema followed by Config.SearchPath or `public`
+func (dialector Dialector) tenantSearchPath(tenant string) string {
+	schemas := []string{dialector.tenantSchema(tenant)}
+	if dialector.Config != nil && len(dialector.Config.SearchPath) > 0 {
+		schemas = append(schemas, dialector.Config.SearchPath...)
+	} else {
+		schemas = append(schemas, "public")
+	}
+	return searchPath(schemas)
+}
+
+// dialectorOf returns the postgres dialector, the gorm.DB holds a pointer while the Migrator holds a value
+func dialectorOf(dialector gorm.Dialector) (Dialector, bool) {
+	switch d := dialector.(type) {
+	case Dialector:
+		return d, true
+	case *Dialector:
+		return *d, d != nil
+	}
+	return Dialector{}, false
+}
+
+// statementSettings returns the run-time settings of a statement, with the search path of its tenant
+func statementSettings(db *gorm.DB) map[string]string {
+	settings := SettingsFromContext(db.Statement.Context)
+	tenant := TenantFromContext(db.Statement.Context)
+	if tenant == "" {
+		return settings
+	}
+	if _, ok := settings["search_path"]; ok {
+		return settings
+	}
+
+	dialector, ok := dialectorOf(db.Dialector)
+	if !ok {
+		return settings
+	}
+	merged := make(map[string]string, len(settings)+1)
+	for name, value := range settings {
+		merged[name] = value
+	}
+	merged["search_path"] = dialector.tenantSearchPath(tenant)
+	return merged
+}
+
+// MigrateTenants creates the schema of each tenant if it doesn't exist and runs AutoMigrate of values in it,
+// tenants are migrated concurrently, a failing tenant doesn't stop the others and the errors of all tenants are returned
+//
+//	err := db.Migrator().(postgres.Migrator).MigrateTenants(postgres.TenantMigration{
+//		Tenants:  []string{"acme", "globex"},
+//		Progress: func(tenant string, done, total int, err error) { log.Printf("%s migrated (%d/%d): %v", tenant, done, total, err) },
+//	}, &Order{}, &Invoice{})
+func (m Migrator) MigrateTenants(config TenantMigration, values ...interface{}) error {
+	concurrency := config.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultTenantConcurrency
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		errs      []error
+		done      int
+		semaphore = make(chan struct{}, concurrency)
+	)
+	for _, tenant := range config.Tenants {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(tenant string) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+
+			err := m.migrateTenant(tenant, values...)
+
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to migrate tenant %s: %w", tenant, err))
+			}
+			if config.Progress != nil {
+				config.Progress(tenant, done, len(config.Tenants), err)
+			}
+		}(tenant)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (m Migrator) migrateTenant(tenant string, values ...interface{}) error {
+	tx := WithTenant(m.DB, tenant)
+	dialector, _ := dialectorOf(m.Dialector)
+	if err := tx.Migrator().(Migrator).CreateSchema(dialector.tenantSchema(tenant)); err != nil {
+		return err
+	}
+	return tx.Migrator().AutoMigrate(values...)
+}
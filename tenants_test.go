@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"database/sql"
+	"sort"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestWithTenant(t *testing.T) {
+	db := testDB(t, Config{TenantSchema: func(tenant string) string { return "tenant_" + tenant }})
+
+	tx := WithTenant(db, "acme")
+	if settings := statementSettings(tx); settings["search_path"] != `"tenant_acme", "public"` {
+		t.Errorf("expected the tenant search path, got %v", settings)
+	}
+	if currentSchema, _ := tx.Migrator().(Migrator).CurrentSchema(&gorm.Statement{}, "orders"); currentSchema != "tenant_acme" {
+		t.Errorf("expected the tenant schema, got %v", currentSchema)
+	}
+
+	tx = WithSearchPath(WithTenant(db, "acme"), "shared")
+	if settings := statementSettings(tx); settings["search_path"] != `"shared"` {
+		t.Errorf("expected an explicit search path to win over the tenant, got %v", settings)
+	}
+
+	if settings := statementSettings(db); len(settings) != 0 {
+		t.Errorf("expected no settings without tenant, got %v", settings)
+	}
+}
+
+func TestMigrator_MigrateTenants(t *testing.T) {
+	sqlDB, err := sql.Open("pgx", "host=127.0.0.1 port=1 connect_timeout=1")
+	if err != nil {
+		t.Fatalf("failed to open sql db, got error %v", err)
+	}
+	db := testDB(t, Config{Conn: sqlDB})
+
+	var (
+		mu       sync.Mutex
+		migrated []string
+		lastDone int
+	)
+	err = db.Migrator().(Migrator).MigrateTenants(TenantMigration{
+		Tenants:     []string{"acme", "globex", "initech"},
+		Concurrency: 2,
+		Progress: func(tenant string, done, total int, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			migrated = append(migrated, tenant)
+			lastDone = done
+			if total != 3 || err == nil {
+				t.Errorf("unexpected progress of %s, total %v, error %v", tenant, total, err)
+			}
+		},
+	}, &viewUser{})
+	if err == nil {
+		t.Fatalf("expected the errors of the tenants")
+	}
+
+	sort.Strings(migrated)
+	if len(migrated) != 3 || migrated[0] != "acme" || migrated[2] != "initech" || lastDone != 3 {
+		t.Errorf("expected the progress of every tenant, got %v", migrated)
+	}
+}
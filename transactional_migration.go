@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"regexp"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+const transactionalMigrationKey = "postgres:transactional_migration"
+
+// statements that can't run inside a transaction block, or whose effect can't be used before the transaction commits
+var nonTransactionalPattern = regexp.MustCompile(`(?is)\bCONCURRENTLY\b|^\s*ALTER\s+TYPE\s.+\sADD\s+VALUE\b`)
+
+// comments on a deferred index have to wait until it is created
+var commentOnIndexPattern = regexp.MustCompile(`(?i)^\s*COMMENT\s+ON\s+INDEX\b`)
+
+// WithTransactionalMigration returns a session whose AutoMigrate runs in one transaction, rolled back as a whole on error.
+// `CONCURRENTLY` operations and enum `ADD VALUE` statements are deferred until the transaction is committed
+//
+//	postgres.WithTransactionalMigration(db).AutoMigrate(&User{}, &Pet{})
+func WithTransactionalMigration(db *gorm.DB) *gorm.DB {
+	return db.Set(transactionalMigrationKey, true)
+}
+
+func transactionalMigrationOf(db *gorm.DB) bool {
+	v, ok := db.Get(transactionalMigrationKey)
+	enabled, _ := v.(bool)
+	return ok && enabled
+}
+
+func (m Migrator) autoMigrateInTransaction(values ...interface{}) error {
+	var (
+		pool      = m.DB.Statement.ConnPool
+		deferring = &deferringTx{}
+	)
+	if err := m.DB.Transaction(func(tx *gorm.DB) error {
+		// clone the statement before replacing its connection pool
+		tx = tx.Session(&gorm.Session{Context: tx.Statement.Context})
+		deferring.ConnPool = tx.Statement.ConnPool
+		tx.Statement.ConnPool = deferring
+		return tx.Migrator().(Migrator).autoMigrate(values...)
+	}); err != nil {
+		return err
+	}
+
+	for _, statement := range deferring.statements {
+		if _, err := pool.ExecContext(m.DB.Statement.Context, statement.query, statement.args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type deferredStatement struct {
+	query string
+	args  []interface{}
+}
+
+// deferringTx wraps the migration transaction, queueing the statements that have to run after it is committed
+type deferringTx struct {
+	gorm.ConnPool
+	mu         sync.Mutex
+	statements []deferredStatement
+}
+
+func (tx *deferringTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if nonTransactionalPattern.MatchString(query) || (len(tx.statements) > 0 && commentOnIndexPattern.MatchString(query)) {
+		tx.statements = append(tx.statements, deferredStatement{query: query, args: args})
+		return driver.RowsAffected(0), nil
+	}
+	return tx.ConnPool.ExecContext(ctx, query, args...)
+}
+
+func (tx *deferringTx) Commit() error {
+	return tx.ConnPool.(gorm.TxCommitter).Commit()
+}
+
+func (tx *deferringTx) Rollback() error {
+	return tx.ConnPool.(gorm.TxCommitter).Rollback()
+}
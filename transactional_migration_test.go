@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+type recordingConnPool struct {
+	gorm.ConnPool
+	executed []string
+}
+
+func (pool *recordingConnPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	pool.executed = append(pool.executed, query)
+	return nil, nil
+}
+
+func Test_deferringTx_ExecContext(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		deferred bool
+	}{
+		{name: "it should execute create table", query: `CREATE TABLE "users" ("id" bigserial)`, deferred: false},
+		{name: "it should execute create index", query: `CREATE INDEX "idx_users_name" ON "users" ("name")`, deferred: false},
+		{name: "it should defer create index concurrently", query: `CREATE INDEX CONCURRENTLY "idx_users_name" ON "users" ("name")`, deferred: true},
+		{name: "it should defer drop index concurrently", query: `DROP INDEX CONCURRENTLY IF EXISTS "public"."idx_users_name"`, deferred: true},
+		{name: "it should defer enum add value", query: `ALTER TYPE "mood" ADD VALUE IF NOT EXISTS 'happy'`, deferred: true},
+		{name: "it should execute enum rename value", query: `ALTER TYPE "mood" RENAME VALUE 'sad' TO 'blue'`, deferred: false},
+		{name: "it should execute alter table add column", query: `ALTER TABLE "users" ADD "value" text`, deferred: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := &recordingConnPool{}
+			tx := &deferringTx{ConnPool: pool}
+			if _, err := tx.ExecContext(context.Background(), tt.query); err != nil {
+				t.Fatalf("failed to exec, got error %v", err)
+			}
+
+			if deferred := len(tx.statements) == 1 && len(pool.executed) == 0; deferred != tt.deferred {
+				t.Errorf("deferred = %v, want %v", deferred, tt.deferred)
+			}
+		})
+	}
+}
+
+func Test_deferringTx_commentOnDeferredIndex(t *testing.T) {
+	var (
+		pool = &recordingConnPool{}
+		tx   = &deferringTx{ConnPool: pool}
+		ctx  = context.Background()
+	)
+
+	tx.ExecContext(ctx, `COMMENT ON INDEX "idx_users_age" IS 'age'`)
+	if len(pool.executed) != 1 {
+		t.Errorf("expected index comments to be executed while nothing is deferred")
+	}
+
+	tx.ExecContext(ctx, `CREATE INDEX CONCURRENTLY "idx_users_name" ON "users" ("name")`)
+	tx.ExecContext(ctx, `COMMENT ON INDEX "idx_users_name" IS 'name'`)
+	if len(pool.executed) != 1 || len(tx.statements) != 2 {
+		t.Errorf("expected index comments to be deferred after a deferred index, got %v deferred", len(tx.statements))
+	}
+}
+
+func TestWithTransactionalMigration(t *testing.T) {
+	db := testDB(t, Config{})
+
+	if transactionalMigrationOf(db) {
+		t.Errorf("expected transactional migration to be disabled by default")
+	}
+	if !transactionalMigrationOf(WithTransactionalMigration(db)) {
+		t.Errorf("expected transactional migration to be enabled")
+	}
+}
@@ -0,0 +1,563 @@
+package postgres
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TriggerTiming when a trigger fires
+type TriggerTiming string
+
+const (
+	TriggerBefore    TriggerTiming = "BEFORE"
+	TriggerAfter     TriggerTiming = "AFTER"
+	TriggerInsteadOf TriggerTiming = "INSTEAD OF"
+)
+
+// TriggerEvent the statement firing a trigger, e.g. `UPDATE OF name` for updates of some columns only
+type TriggerEvent string
+
+const (
+	TriggerInsert   TriggerEvent = "INSERT"
+	TriggerUpdate   TriggerEvent = "UPDATE"
+	TriggerDelete   TriggerEvent = "DELETE"
+	TriggerTruncate TriggerEvent = "TRUNCATE"
+)
+
+// Function a function created with `CREATE OR REPLACE FUNCTION`
+type Function struct {
+	Name string
+	// Arguments the argument list as printed by the database, e.g. `a integer, b integer` rather than `a int, b int`
+	Arguments string
+	// Returns defaults to trigger
+	Returns string
+	// Language defaults to plpgsql
+	Language string
+	// Options the options of the function, e.g. `SECURITY DEFINER` or `SET search_path FROM CURRENT`, the security, volatility,
+	// strictness and the names of the set parameters are compared with the database, not the values of the parameters
+	Options string
+	Body    string
+}
+
+// functionDefinition a function as stored in the catalog
+type functionDefinition struct {
+	Source          string
+	Arguments       string
+	Returns         string
+	Language        string
+	SecurityDefiner bool
+	Volatility      string
+	Strict          bool
+	// Config the `name=value` parameters set by the function, one per line
+	Config string
+}
+
+// functionSQL selects the functionDefinition of a function
+const functionSQL = `
+SELECT
+	p.prosrc AS source,
+	pg_get_function_arguments(p.oid) AS arguments,
+	pg_get_function_result(p.oid) AS returns,
+	l.lanname AS language,
+	p.prosecdef AS security_definer,
+	p.provolatile::text AS volatility,
+	p.proisstrict AS strict,
+	coalesce(array_to_string(p.proconfig, E'\n'), '') AS config
+FROM
+	pg_catalog.pg_proc p
+	JOIN pg_catalog.pg_namespace n ON n.oid = p.pronamespace
+	JOIN pg_catalog.pg_language l ON l.oid = p.prolang
+WHERE
+	n.nspname = ? AND p.proname = ?
+`
+
+// createOrReplaceTriggerVersion the first server_version_num supporting `CREATE OR REPLACE TRIGGER`
+const createOrReplaceTriggerVersion = 140000
+
+var functionSetPattern = regexp.MustCompile(`(?i)\bSET\s+([a-z_][a-z0-9_.]*)`)
+
+// Trigger a trigger of a table, executing Function
+type Trigger struct {
+	Name   string
+	Timing TriggerTiming
+	Events []TriggerEvent
+	// ForEachStatement fires the trigger once per statement instead of once per row
+	ForEachStatement bool
+	// When the condition of the rows firing the trigger, e.g. `OLD.* IS DISTINCT FROM NEW.*`
+	When     string
+	Function Function
+	// Arguments the string arguments passed to the function as TG_ARGV
+	Arguments []string
+	Disabled  bool
+}
+
+// the bits of pg_trigger.tgtype
+const (
+	triggerTypeRow      = 1 << 0
+	triggerTypeBefore   = 1 << 1
+	triggerTypeInsert   = 1 << 2
+	triggerTypeDelete   = 1 << 3
+	triggerTypeUpdate   = 1 << 4
+	triggerTypeTruncate = 1 << 5
+	triggerTypeInstead  = 1 << 6
+)
+
+// triggersSQL lists the triggers of a table, the arguments are the NUL terminated strings of tgargs and the columns the ones of
+// `UPDATE OF`
+const triggersSQL = `
+SELECT
+	t.tgname,
+	pg_get_triggerdef(t.oid),
+	t.tgenabled <> 'D',
+	t.tgtype,
+	p.proname,
+	t.tgnargs,
+	t.tgargs,
+	t.tgqual IS NOT NULL,
+	array_to_string(ARRAY(SELECT quote_ident(a.attname) FROM unnest(t.tgattr) AS k(n) JOIN pg_catalog.pg_attribute a ON a.attrelid = t.tgrelid AND a.attnum = k.n), ',')
+FROM
+	pg_catalog.pg_trigger t
+	JOIN pg_catalog.pg_class c ON c.oid = t.tgrelid
+	JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+	JOIN pg_catalog.pg_proc p ON p.oid = t.tgfoid
+WHERE
+	n.nspname = ? AND c.relname = ? AND NOT t.tgisinternal
+ORDER BY
+	t.tgname
+`
+
+// TriggerDefinition a trigger of a table, with its definition as deparsed by the database and its parts as stored in the catalog
+type TriggerDefinition struct {
+	Name       string
+	Definition string
+	Enabled    bool
+	Timing     TriggerTiming
+	Events     []TriggerEvent
+	// ForEachStatement whether the trigger fires once per statement instead of once per row
+	ForEachStatement bool
+	// Function the name of the function executed, without its schema
+	Function  string
+	Arguments []string
+	// Conditional whether the trigger has a `WHEN` condition
+	Conditional bool
+}
+
+// Triggered a model whose triggers and their functions are created by AutoMigrate, the functions are replaced when their body,
+// arguments, result, language or options changed and the triggers when their timing, events, level, function or arguments changed, or when a `WHEN` condition is
+// added or removed, as the database rewrites the conditions a changed condition isn't detected, triggers of the table not
+// declared by the model are kept
+//
+//	func (Order) Triggers() []postgres.Trigger {
+//		return []postgres.Trigger{{
+//			Name:   "orders_notify",
+//			Timing: postgres.TriggerAfter,
+//			Events: []postgres.TriggerEvent{postgres.TriggerInsert},
+//			Function: postgres.Function{Name: "notify_order", Body: `BEGIN PERFORM pg_notify('orders', NEW.id::text); RETURN NEW; END;`},
+//		}}
+//	}
+type Triggered interface {
+	Triggers() []Trigger
+}
+
+func (function Function) returns() string {
+	if function.Returns == "" {
+		return "trigger"
+	}
+	return function.Returns
+}
+
+func (function Function) language() string {
+	if function.Language == "" {
+		return "plpgsql"
+	}
+	return function.Language
+}
+
+func (function Function) options() string {
+	if function.Options == "" {
+		return ""
+	}
+	return " " + function.Options
+}
+
+// CreateFunction creates or replaces function
+func (m Migrator) CreateFunction(function Function) error {
+	// without vars, so `?` and `@` of the body aren't taken as placeholders
+	return m.DB.Exec(
+		"CREATE OR REPLACE FUNCTION " + m.DB.Statement.Quote(clause.Table{Name: function.Name}) + "(" + function.Arguments + ") RETURNS " +
+			function.returns() + " LANGUAGE " + function.language() + function.options() + " AS " + dollarQuote(function.Body),
+	).Error
+}
+
+// DropFunction drops the function name if it exists, cascade drops the triggers executing it too
+func (m Migrator) DropFunction(name string, cascade bool) error {
+	dropSQL := "DROP FUNCTION IF EXISTS ?"
+	if cascade {
+		dropSQL += " CASCADE"
+	}
+	return m.DB.Exec(dropSQL, clause.Table{Name: name}).Error
+}
+
+// HasFunction returns whether the function name exists
+func (m Migrator) HasFunction(name string) bool {
+	_, ok, _ := m.functionDefinition(name)
+	return ok
+}
+
+// functionDefinition returns the function name as stored in the catalog
+func (m Migrator) functionDefinition(name string) (definition functionDefinition, ok bool, err error) {
+	currentSchema, function := m.CurrentSchema(&gorm.Statement{}, name)
+	var definitions []functionDefinition
+	if err = m.queryRaw(functionSQL, currentSchema, function).Scan(&definitions).Error; err != nil || len(definitions) == 0 {
+		return definition, false, err
+	}
+	return definitions[0], true, nil
+}
+
+// functionChanged returns whether the function current differs from the declared function, the options are compared by their
+// catalog values
+func functionChanged(current functionDefinition, function Function) bool {
+	normalize := func(s string) string {
+		return strings.ToLower(strings.TrimSpace(whitespacePattern.ReplaceAllString(s, " ")))
+	}
+
+	options := " " + strings.ToUpper(normalize(function.Options)) + " "
+	volatility := "v"
+	switch {
+	case strings.Contains(options, " IMMUTABLE "):
+		volatility = "i"
+	case strings.Contains(options, " STABLE "):
+		volatility = "s"
+	}
+	strict := strings.Contains(options, " STRICT ") || strings.Contains(options, " RETURNS NULL ON NULL INPUT ")
+
+	var settings, currentSettings []string
+	for _, match := range functionSetPattern.FindAllStringSubmatch(function.Options, -1) {
+		settings = append(settings, strings.ToLower(match[1]))
+	}
+	for _, setting := range strings.Split(current.Config, "\n") {
+		if name, _, ok := strings.Cut(setting, "="); ok {
+			currentSettings = append(currentSettings, strings.ToLower(name))
+		}
+	}
+	sort.Strings(settings)
+	sort.Strings(currentSettings)
+
+	return strings.TrimSpace(current.Source) != strings.TrimSpace(function.Body) ||
+		normalize(current.Arguments) != normalize(function.Arguments) ||
+		normalize(current.Returns) != normalize(function.returns()) ||
+		current.Language != normalize(function.language()) ||
+		current.SecurityDefiner != strings.Contains(options, " SECURITY DEFINER ") ||
+		current.Volatility != volatility || current.Strict != strict ||
+		strings.Join(currentSettings, ",") != strings.Join(settings, ",")
+}
+
+// CreateTrigger creates or replaces trigger on value's table, its function is expected to exist, before Postgres 14 which
+// lacks `CREATE OR REPLACE TRIGGER` the trigger is dropped and created
+func (m Migrator) CreateTrigger(value interface{}, trigger Trigger) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		version, err := m.serverVersion()
+		if err != nil {
+			return err
+		}
+		createSQL := "CREATE OR REPLACE "
+		if version < createOrReplaceTriggerVersion {
+			if err := m.DropTrigger(value, trigger.Name); err != nil {
+				return err
+			}
+			createSQL = "CREATE "
+		}
+		if err := m.DB.Exec(createSQL + m.triggerSQL(stmt, trigger)).Error; err != nil {
+			return err
+		}
+		if trigger.Disabled {
+			return m.DisableTrigger(value, trigger.Name)
+		}
+		return nil
+	})
+}
+
+// DropTrigger drops the trigger name of value's table
+func (m Migrator) DropTrigger(value interface{}, name string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		return m.DB.Exec("DROP TRIGGER IF EXISTS ? ON ?", clause.Column{Name: name}, m.CurrentTable(stmt)).Error
+	})
+}
+
+// EnableTrigger enables the trigger name of value's table
+func (m Migrator) EnableTrigger(value interface{}, name string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		return m.DB.Exec("ALTER TABLE ? ENABLE TRIGGER ?", m.CurrentTable(stmt), clause.Column{Name: name}).Error
+	})
+}
+
+// DisableTrigger disables the trigger name of value's table, it doesn't fire until it is enabled again
+func (m Migrator) DisableTrigger(value interface{}, name string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		return m.DB.Exec("ALTER TABLE ? DISABLE TRIGGER ?", m.CurrentTable(stmt), clause.Column{Name: name}).Error
+	})
+}
+
+// HasTrigger returns whether value's table has the trigger name
+func (m Migrator) HasTrigger(value interface{}, name string) bool {
+	var count int64
+	m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		currentSchema, curTable := m.CurrentSchema(stmt, stmt.Table)
+		return m.queryRaw(
+			"SELECT count(*) FROM pg_catalog.pg_trigger t JOIN pg_catalog.pg_class c ON c.oid = t.tgrelid JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = ? AND c.relname = ? AND t.tgname = ? AND NOT t.tgisinternal",
+			currentSchema, curTable, name,
+		).Scan(&count).Error
+	})
+	return count > 0
+}
+
+// GetTriggers returns the triggers of value's table, without the internal ones of constraints
+func (m Migrator) GetTriggers(value interface{}) (triggers []TriggerDefinition, err error) {
+	err = m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		currentSchema, curTable := m.CurrentSchema(stmt, stmt.Table)
+		rows, err := m.queryRaw(triggersSQL, currentSchema, curTable).Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				trigger         TriggerDefinition
+				tgtype, tgnargs int
+				tgargs          []byte
+				columns         string
+			)
+			if err := rows.Scan(
+				&trigger.Name, &trigger.Definition, &trigger.Enabled, &tgtype, &trigger.Function, &tgnargs, &tgargs, &trigger.Conditional, &columns,
+			); err != nil {
+				return err
+			}
+			trigger.Timing, trigger.Events, trigger.ForEachStatement = parseTriggerType(tgtype, columns)
+			trigger.Arguments = parseTriggerArguments(tgargs, tgnargs)
+			triggers = append(triggers, trigger)
+		}
+		return rows.Err()
+	})
+	return
+}
+
+// parseTriggerType returns the timing, the events and the level of pg_trigger.tgtype, columns are the quoted columns of
+// `UPDATE OF`
+func parseTriggerType(tgtype int, columns string) (timing TriggerTiming, events []TriggerEvent, forEachStatement bool) {
+	switch {
+	case tgtype&triggerTypeInstead != 0:
+		timing = TriggerInsteadOf
+	case tgtype&triggerTypeBefore != 0:
+		timing = TriggerBefore
+	default:
+		timing = TriggerAfter
+	}
+	if tgtype&triggerTypeInsert != 0 {
+		events = append(events, TriggerInsert)
+	}
+	if tgtype&triggerTypeUpdate != 0 {
+		if columns != "" {
+			events = append(events, TriggerEvent("UPDATE OF "+strings.ReplaceAll(columns, ",", ", ")))
+		} else {
+			events = append(events, TriggerUpdate)
+		}
+	}
+	if tgtype&triggerTypeDelete != 0 {
+		events = append(events, TriggerDelete)
+	}
+	if tgtype&triggerTypeTruncate != 0 {
+		events = append(events, TriggerTruncate)
+	}
+	return timing, events, tgtype&triggerTypeRow == 0
+}
+
+// parseTriggerArguments returns the nargs NUL terminated arguments of pg_trigger.tgargs
+func parseTriggerArguments(tgargs []byte, nargs int) []string {
+	arguments := make([]string, 0, nargs)
+	for _, argument := range bytes.SplitN(tgargs, []byte{0}, nargs+1) {
+		if len(arguments) == nargs {
+			break
+		}
+		arguments = append(arguments, string(argument))
+	}
+	return arguments
+}
+
+// triggerEvents returns the events of a trigger as a set, with the sorted columns of `UPDATE OF` unquoted and case folded as
+// the database would
+func triggerEvents(events []TriggerEvent) map[string]bool {
+	set := map[string]bool{}
+	for _, event := range events {
+		fields := strings.Fields(string(event))
+		if len(fields) > 2 && strings.EqualFold(fields[0], "UPDATE") && strings.EqualFold(fields[1], "OF") {
+			list := strings.TrimSpace(string(event))
+			list = strings.TrimSpace(list[strings.Index(strings.ToUpper(list), " OF ")+4:])
+			var columns []string
+			for _, column := range strings.Split(list, ",") {
+				if column = strings.TrimSpace(column); strings.HasPrefix(column, `"`) && strings.HasSuffix(column, `"`) {
+					column = strings.ReplaceAll(column[1:len(column)-1], `""`, `"`)
+				} else {
+					column = strings.ToLower(column)
+				}
+				columns = append(columns, column)
+			}
+			sort.Strings(columns)
+			set["UPDATE OF "+strings.Join(columns, ",")] = true
+			continue
+		}
+		set[strings.ToUpper(strings.Join(fields, " "))] = true
+	}
+	return set
+}
+
+// triggerChanged returns whether the trigger current differs from the declared trigger, its parts are compared as stored in
+// the catalog as the database rewrites the deparsed conditions
+func triggerChanged(current TriggerDefinition, trigger Trigger) bool {
+	if !strings.EqualFold(string(current.Timing), strings.Join(strings.Fields(string(trigger.Timing)), " ")) ||
+		current.ForEachStatement != trigger.ForEachStatement || current.Conditional != (strings.TrimSpace(trigger.When) != "") {
+		return true
+	}
+
+	function := trigger.Function.Name
+	if idx := strings.LastIndex(function, "."); idx != -1 {
+		function = function[idx+1:]
+	}
+	if current.Function != function {
+		return true
+	}
+
+	if len(current.Arguments) != len(trigger.Arguments) {
+		return true
+	}
+	for i, argument := range trigger.Arguments {
+		if current.Arguments[i] != argument {
+			return true
+		}
+	}
+
+	currentEvents, events := triggerEvents(current.Events), triggerEvents(trigger.Events)
+	if len(currentEvents) != len(events) {
+		return true
+	}
+	for event := range events {
+		if !currentEvents[event] {
+			return true
+		}
+	}
+	return false
+}
+
+// triggersOf returns the triggers declared by value
+func triggersOf(value interface{}) []Trigger {
+	if triggered, ok := value.(Triggered); ok {
+		return triggered.Triggers()
+	}
+	return nil
+}
+
+// migrateTriggers creates the functions and triggers declared by value, with diff only the changes from the database are applied
+func (m Migrator) migrateTriggers(value interface{}, diff bool) error {
+	triggers := triggersOf(value)
+	if len(triggers) == 0 {
+		return nil
+	}
+
+	var existing []TriggerDefinition
+	if diff {
+		var err error
+		if existing, err = m.GetTriggers(value); err != nil {
+			return err
+		}
+	}
+
+	created := map[string]bool{}
+	for _, trigger := range triggers {
+		if err := m.createAuditTable(trigger); err != nil {
+			return err
+		}
+		if name := trigger.Function.Name; !created[name] {
+			created[name] = true
+			current, ok, err := m.functionDefinition(name)
+			if err != nil {
+				return err
+			}
+			if !ok || functionChanged(current, trigger.Function) {
+				if err := m.CreateFunction(trigger.Function); err != nil {
+					return err
+				}
+			}
+		}
+
+		var current *TriggerDefinition
+		for idx := range existing {
+			if existing[idx].Name == trigger.Name {
+				current = &existing[idx]
+			}
+		}
+
+		switch {
+		case current == nil || triggerChanged(*current, trigger):
+			if err := m.CreateTrigger(value, trigger); err != nil {
+				return err
+			}
+		case current.Enabled && trigger.Disabled:
+			if err := m.DisableTrigger(value, trigger.Name); err != nil {
+				return err
+			}
+		case !current.Enabled && !trigger.Disabled:
+			if err := m.EnableTrigger(value, trigger.Name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// triggerSQL returns the `TRIGGER` statement of trigger on the table of stmt, without vars so `?` and `@` of its condition
+// aren't taken as placeholders
+func (m Migrator) triggerSQL(stmt *gorm.Statement, trigger Trigger) string {
+	events := make([]string, 0, len(trigger.Events))
+	for _, event := range trigger.Events {
+		events = append(events, string(event))
+	}
+
+	sql := "TRIGGER " + m.DB.Statement.Quote(clause.Column{Name: trigger.Name}) + " " + string(trigger.Timing) + " " +
+		strings.Join(events, " OR ") + " ON " + m.quoteTable(stmt) + " FOR EACH "
+	if trigger.ForEachStatement {
+		sql += "STATEMENT"
+	} else {
+		sql += "ROW"
+	}
+	if trigger.When != "" {
+		sql += " WHEN (" + trigger.When + ")"
+	}
+
+	arguments := make([]string, 0, len(trigger.Arguments))
+	for _, argument := range trigger.Arguments {
+		arguments = append(arguments, "'"+strings.ReplaceAll(argument, "'", "''")+"'")
+	}
+	return sql + " EXECUTE FUNCTION " + m.DB.Statement.Quote(clause.Table{Name: trigger.Function.Name}) + "(" + strings.Join(arguments, ", ") + ")"
+}
+
+// quoteTable returns the quoted table of stmt
+func (m Migrator) quoteTable(stmt *gorm.Statement) string {
+	if table, ok := m.CurrentTable(stmt).(clause.Expr); ok {
+		return table.SQL
+	}
+	return m.DB.Statement.Quote(m.CurrentTable(stmt))
+}
+
+// dollarQuote quotes body with a dollar quote tag it doesn't contain
+func dollarQuote(body string) string {
+	tag := "$function$"
+	for strings.Contains(body, tag) {
+		tag = "$" + strings.Trim(tag, "$") + "_$"
+	}
+	return tag + body + tag
+}
@@ -0,0 +1,234 @@
+package postgres
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+type triggerOrder struct {
+	ID     uint64
+	Status string
+}
+
+func (triggerOrder) Triggers() []Trigger {
+	return []Trigger{{
+		Name:      "orders_notify",
+		Timing:    TriggerAfter,
+		Events:    []TriggerEvent{TriggerInsert, "UPDATE OF status"},
+		When:      "NEW.status <> 'draft'",
+		Function:  Function{Name: "notify_order", Body: "BEGIN PERFORM pg_notify('orders', NEW.id::text); RETURN NEW; END;"},
+		Arguments: []string{"it's"},
+	}}
+}
+
+func TestMigrator_triggerSQL(t *testing.T) {
+	s, err := schema.Parse(&triggerOrder{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("failed to parse schema, got error %v", err)
+	}
+	dialector := Dialector{Config: &Config{}}
+	db := &gorm.DB{Config: &gorm.Config{Dialector: dialector}}
+	db.Statement = &gorm.Statement{DB: db}
+	m := Migrator{migrator.Migrator{Config: migrator.Config{DB: db, Dialector: dialector}}}
+	stmt := &gorm.Statement{DB: db, Schema: s, Table: s.Table}
+
+	trigger := triggerOrder{}.Triggers()[0]
+	sql := m.triggerSQL(stmt, trigger)
+	if want := `TRIGGER "orders_notify" AFTER INSERT OR UPDATE OF status ON "trigger_orders" FOR EACH ROW WHEN (NEW.status <> 'draft') EXECUTE FUNCTION "notify_order"('it''s')`; sql != want {
+		t.Errorf("triggerSQL() = %v, want %v", sql, want)
+	}
+
+}
+
+func Test_triggerChanged(t *testing.T) {
+	// CREATE TRIGGER orders_notify AFTER INSERT OR UPDATE OF status ON public.trigger_orders FOR EACH ROW
+	// WHEN (((new.status)::text <> 'draft'::text)) EXECUTE FUNCTION notify_order('it''s')
+	timing, events, forEachStatement := parseTriggerType(triggerTypeRow|triggerTypeInsert|triggerTypeUpdate, "status")
+	current := TriggerDefinition{
+		Name: "orders_notify", Timing: timing, Events: events, ForEachStatement: forEachStatement,
+		Function: "notify_order", Arguments: parseTriggerArguments([]byte("it's\x00"), 1), Conditional: true,
+	}
+
+	tests := []struct {
+		name   string
+		change func(trigger *Trigger)
+		want   bool
+	}{
+		{name: "it should match the catalog parts", change: func(trigger *Trigger) {}},
+		{name: "it should ignore the case and order of the events", change: func(trigger *Trigger) {
+			trigger.Events = []TriggerEvent{`update of "status"`, "insert"}
+		}},
+		{name: "it should ignore the schema of the function", change: func(trigger *Trigger) { trigger.Function.Name = "public.notify_order" }},
+		{name: "it should detect a changed timing", change: func(trigger *Trigger) { trigger.Timing = TriggerBefore }, want: true},
+		{name: "it should detect changed events", change: func(trigger *Trigger) { trigger.Events = []TriggerEvent{TriggerInsert, TriggerUpdate} }, want: true},
+		{name: "it should detect changed update columns", change: func(trigger *Trigger) {
+			trigger.Events = []TriggerEvent{TriggerInsert, "UPDATE OF status, total"}
+		}, want: true},
+		{name: "it should detect a changed level", change: func(trigger *Trigger) { trigger.ForEachStatement = true; trigger.When = "" }, want: true},
+		{name: "it should detect a removed condition", change: func(trigger *Trigger) { trigger.When = "" }, want: true},
+		{name: "it should detect a changed function", change: func(trigger *Trigger) { trigger.Function.Name = "notify_orders" }, want: true},
+		{name: "it should detect changed arguments", change: func(trigger *Trigger) { trigger.Arguments = []string{"its"} }, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trigger := triggerOrder{}.Triggers()[0]
+			tt.change(&trigger)
+			if got := triggerChanged(current, trigger); got != tt.want {
+				t.Errorf("triggerChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseTriggerType(t *testing.T) {
+	timing, events, forEachStatement := parseTriggerType(triggerTypeBefore|triggerTypeDelete|triggerTypeTruncate, "")
+	if timing != TriggerBefore || !reflect.DeepEqual(events, []TriggerEvent{TriggerDelete, TriggerTruncate}) || !forEachStatement {
+		t.Errorf("parseTriggerType() = %v, %v, %v", timing, events, forEachStatement)
+	}
+	timing, events, forEachStatement = parseTriggerType(triggerTypeRow|triggerTypeInstead|triggerTypeUpdate, `a,"B"`)
+	if timing != TriggerInsteadOf || !reflect.DeepEqual(events, []TriggerEvent{`UPDATE OF a, "B"`}) || forEachStatement {
+		t.Errorf("parseTriggerType() = %v, %v, %v", timing, events, forEachStatement)
+	}
+}
+
+func Test_parseTriggerArguments(t *testing.T) {
+	if got, want := parseTriggerArguments([]byte("a\x00\x00b,c\x00"), 3), []string{"a", "", "b,c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTriggerArguments() = %q, want %q", got, want)
+	}
+	if got := parseTriggerArguments(nil, 0); len(got) != 0 {
+		t.Errorf("parseTriggerArguments() = %q, want no arguments", got)
+	}
+}
+
+func Test_dollarQuote(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "it should quote with the function tag", body: "BEGIN RETURN NEW; END;", want: "$function$BEGIN RETURN NEW; END;$function$"},
+		{name: "it should pick a tag the body doesn't contain", body: "SELECT '$function$'", want: "$function_$SELECT '$function$'$function_$"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dollarQuote(tt.body); got != tt.want {
+				t.Errorf("dollarQuote() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_functionChanged(t *testing.T) {
+	current := functionDefinition{Source: "BEGIN RETURN NEW; END;", Returns: "trigger", Language: "plpgsql", Volatility: "v"}
+	tests := []struct {
+		name     string
+		current  functionDefinition
+		function Function
+		want     bool
+	}{
+		{name: "it should keep an unchanged function", current: current, function: Function{Body: " BEGIN RETURN NEW; END; "}},
+		{name: "it should replace a function whose body changed", current: current, function: Function{Body: "BEGIN RETURN OLD; END;"}, want: true},
+		{
+			name:     "it should replace a function whose arguments changed",
+			current:  current,
+			function: Function{Body: "BEGIN RETURN NEW; END;", Arguments: "a integer"},
+			want:     true,
+		},
+		{
+			name:     "it should replace a function whose result changed",
+			current:  current,
+			function: Function{Body: "BEGIN RETURN NEW; END;", Returns: "event_trigger"},
+			want:     true,
+		},
+		{
+			name:     "it should replace a function whose language changed",
+			current:  current,
+			function: Function{Body: "BEGIN RETURN NEW; END;", Language: "sql"},
+			want:     true,
+		},
+		{
+			name:     "it should replace a function setting a parameter",
+			current:  current,
+			function: Function{Body: "BEGIN RETURN NEW; END;", Options: "SET search_path FROM CURRENT"},
+			want:     true,
+		},
+		{
+			name: "it should keep a function setting the same parameters",
+			current: functionDefinition{
+				Source: "BEGIN RETURN NEW; END;", Returns: "trigger", Language: "plpgsql", Volatility: "s", SecurityDefiner: true,
+				Config: "search_path=app, public\nwork_mem=64MB",
+			},
+			function: Function{Body: "BEGIN RETURN NEW; END;", Options: "STABLE SECURITY DEFINER SET work_mem = '64MB' SET search_path FROM CURRENT"},
+		},
+		{
+			name:     "it should replace a function whose security changed",
+			current:  current,
+			function: Function{Body: "BEGIN RETURN NEW; END;", Options: "SECURITY DEFINER"},
+			want:     true,
+		},
+		{
+			name:     "it should replace a function whose volatility changed",
+			current:  current,
+			function: Function{Body: "BEGIN RETURN NEW; END;", Options: "IMMUTABLE STRICT"},
+			want:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := functionChanged(tt.current, tt.function); got != tt.want {
+				t.Errorf("functionChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrator_CreateTrigger(t *testing.T) {
+	trigger := triggerOrder{}.Triggers()[0]
+	tests := []struct {
+		name    string
+		version int64
+		want    []string
+	}{
+		{
+			name:    "it should replace the trigger from Postgres 14",
+			version: 140005,
+			want:    []string{"CREATE OR REPLACE TRIGGER"},
+		},
+		{
+			name:    "it should drop and create the trigger before Postgres 14",
+			version: 130010,
+			want:    []string{`DROP TRIGGER IF EXISTS "orders_notify" ON "trigger_orders"`, "CREATE TRIGGER"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, connector := stubDB(t, func(query string) ([]string, [][]driver.Value, error) {
+				if strings.Contains(query, "server_version_num") {
+					return []string{"current_setting"}, [][]driver.Value{{tt.version}}, nil
+				}
+				return nil, nil, nil
+			})
+			if err := db.Migrator().(Migrator).CreateTrigger(&triggerOrder{}, trigger); err != nil {
+				t.Fatalf("CreateTrigger() error = %v", err)
+			}
+			var executed []string
+			for _, statement := range connector.statements() {
+				if strings.HasPrefix(statement, "DROP TRIGGER") {
+					executed = append(executed, statement)
+				} else if strings.HasPrefix(statement, "CREATE") {
+					executed = append(executed, strings.SplitAfter(statement, "TRIGGER")[0])
+				}
+			}
+			if !reflect.DeepEqual(executed, tt.want) {
+				t.Errorf("CreateTrigger() executed %q, want %q", executed, tt.want)
+			}
+		})
+	}
+}
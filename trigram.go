@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Similar matches the text column Column similar to Value with the `pg_trgm` operator `%`,
+// above the `pg_trgm.similarity_threshold`, see WithSimilarityThreshold
+//
+//	db.Where(postgres.Similar{Column: "name", Value: "jinzu"}).Clauses(postgres.SimilarityDistance{Column: "name", Value: "jinzu"}.OrderBy()).Find(&users)
+type Similar struct {
+	Column string
+	Value  string
+}
+
+// Build builds the `%` condition
+func (similar Similar) Build(builder clause.Builder) {
+	builder.WriteQuoted(clause.Column{Name: similar.Column})
+	builder.WriteString(" % ")
+	builder.AddVar(builder, similar.Value)
+}
+
+// SimilarityDistance the trigram distance `<->` of the text column Column to Value, one minus their similarity
+type SimilarityDistance struct {
+	Column string
+	Value  string
+}
+
+// Build builds the `<->` distance
+func (distance SimilarityDistance) Build(builder clause.Builder) {
+	builder.WriteQuoted(clause.Column{Name: distance.Column})
+	builder.WriteString(" <-> ")
+	builder.AddVar(builder, distance.Value)
+}
+
+// OrderBy orders by distance, the most similar first, it uses the `gist_trgm_ops` indexes of Column
+func (distance SimilarityDistance) OrderBy() clause.OrderBy {
+	return clause.OrderBy{Expression: clause.Expr{SQL: "?", Vars: []interface{}{distance}}}
+}
+
+// Similarity the trigram similarity of the text column Column and Value, from 0 to 1
+//
+//	db.Model(&User{}).Select("name, ? AS score", postgres.Similarity{Column: "name", Value: "jinzu"}).Scan(&scores)
+type Similarity struct {
+	Column string
+	Value  string
+}
+
+// Build builds the `similarity` call
+func (similarity Similarity) Build(builder clause.Builder) {
+	builder.WriteString("similarity(")
+	builder.WriteQuoted(clause.Column{Name: similarity.Column})
+	builder.WriteString(", ")
+	builder.AddVar(builder, similarity.Value)
+	builder.WriteByte(')')
+}
+
+// WordSimilarity the greatest trigram similarity of Value and a word extent of the text column Column, from 0 to 1
+type WordSimilarity struct {
+	Column string
+	Value  string
+}
+
+// Build builds the `word_similarity` call
+func (similarity WordSimilarity) Build(builder clause.Builder) {
+	builder.WriteString("word_similarity(")
+	builder.AddVar(builder, similarity.Value)
+	builder.WriteString(", ")
+	builder.WriteQuoted(clause.Column{Name: similarity.Column})
+	builder.WriteByte(')')
+}
+
+// WithSimilarityThreshold returns a session matching Similar above threshold, `pg_trgm.similarity_threshold` is set
+// for each statement like the settings of WithSettings
+func WithSimilarityThreshold(db *gorm.DB, threshold float64) *gorm.DB {
+	return WithSettings(db, map[string]string{"pg_trgm.similarity_threshold": strconv.FormatFloat(threshold, 'f', -1, 64)})
+}
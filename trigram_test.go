@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+type trigramUser struct {
+	ID    uint64
+	Name  string `gorm:"index:,type:gin,opclass:gin_trgm_ops"`
+	Email string `gorm:"index:idx_trigram_users_email,type:gist,opclass:gist_trgm_ops;uniqueIndex:idx_trigram_users_email_unique"`
+}
+
+func TestTrigramClauses(t *testing.T) {
+	tx := dryRunDB(t)
+
+	stmt := tx.Where(Similar{Column: "name", Value: "jinzu"}).Clauses(SimilarityDistance{Column: "name", Value: "jinzu"}.OrderBy()).Find(&[]trigramUser{}).Statement
+	if want := `SELECT * FROM "trigram_users" WHERE "name" % $1 ORDER BY "name" <-> $2`; stmt.SQL.String() != want {
+		t.Errorf("expected %v, got %v", want, stmt.SQL.String())
+	}
+
+	stmt = tx.Model(&trigramUser{}).Select("?, ?", Similarity{Column: "name", Value: "jinzu"}, WordSimilarity{Column: "name", Value: "jinzu"}).Find(&[]map[string]interface{}{}).Statement
+	if want := `SELECT similarity("name", $1), word_similarity($2, "name") FROM "trigram_users"`; stmt.SQL.String() != want {
+		t.Errorf("expected %v, got %v", want, stmt.SQL.String())
+	}
+
+	if settings := SettingsFromContext(WithSimilarityThreshold(tx, 0.45).Statement.Context); settings["pg_trgm.similarity_threshold"] != "0.45" {
+		t.Errorf("expected the similarity threshold setting, got %v", settings)
+	}
+}
+
+func TestMigrator_buildIndexOptions_opclass(t *testing.T) {
+	db := testDB(t, Config{})
+	s, err := schema.Parse(&trigramUser{}, &sync.Map{}, db.NamingStrategy)
+	if err != nil {
+		t.Fatalf("failed to parse schema, got error %v", err)
+	}
+	m := db.Migrator().(Migrator)
+	stmt := &gorm.Statement{DB: db, Schema: s, Table: s.Table}
+
+	tests := []struct {
+		name  string
+		index string
+		want  []interface{}
+	}{
+		{name: "it should add the opclass of an unnamed index", index: "idx_trigram_users_name", want: []interface{}{clause.Expr{SQL: `"name" gin_trgm_ops`}}},
+		{name: "it should add the opclass of the named index", index: "idx_trigram_users_email", want: []interface{}{clause.Expr{SQL: `"email" gist_trgm_ops`}}},
+		{name: "it should not add the opclass of another index of the field", index: "idx_trigram_users_email_unique", want: []interface{}{clause.Expr{SQL: `"email"`}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := s.LookIndex(tt.index)
+			if idx == nil {
+				t.Fatalf("failed to find index %v", tt.index)
+			}
+			if got := m.buildIndexOptions(idx.Fields, stmt, idx.Name); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildIndexOptions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
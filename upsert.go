@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OnConflict the `ON CONFLICT` clause of an INSERT, with InsertedColumn the RETURNING clause reports whether each row was
+// inserted or updated
+//
+//	db.Clauses(postgres.OnConflict{
+//		OnConflict: clause.OnConflict{
+//			Columns:     []clause.Column{{Name: "email"}},
+//			TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "deleted_at IS NULL"}}},
+//			DoUpdates:   clause.Set{{Column: clause.Column{Name: "name"}, Value: postgres.Excluded("name")}},
+//			Where:       clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "users.name IS DISTINCT FROM excluded.name"}}},
+//		},
+//		InsertedColumn: "inserted",
+//	}).Create(&users)
+type OnConflict struct {
+	clause.OnConflict
+	// InsertedColumn the column of the model, e.g. a `gorm:"->"` field, receiving `xmax = 0`, true for the inserted rows
+	// and false for the updated ones
+	InsertedColumn string
+}
+
+// Name the name of the clause
+func (OnConflict) Name() string {
+	return "ON CONFLICT"
+}
+
+// Build builds the `ON CONFLICT` clause without its name
+func (onConflict OnConflict) Build(builder clause.Builder) {
+	buildOnConflict(onConflict.OnConflict, builder)
+}
+
+// MergeClause replaces the conflict clause
+func (onConflict OnConflict) MergeClause(c *clause.Clause) {
+	c.Expression = onConflict
+}
+
+// Excluded references the column of the row proposed for insertion in the `DO UPDATE` and `WHERE` clauses of OnConflict
+func Excluded(column string) clause.Column {
+	return clause.Column{Table: "excluded", Name: column}
+}
+
+// buildOnConflict builds onConflict, the constraint name is quoted, and an update without assignments does nothing
+func buildOnConflict(onConflict clause.OnConflict, builder clause.Builder) {
+	if onConflict.OnConstraint != "" {
+		builder.WriteString("ON CONSTRAINT ")
+		builder.WriteQuoted(onConflict.OnConstraint)
+		builder.WriteByte(' ')
+	} else {
+		if len(onConflict.Columns) > 0 {
+			builder.WriteByte('(')
+			for idx, column := range onConflict.Columns {
+				if idx > 0 {
+					builder.WriteByte(',')
+				}
+				builder.WriteQuoted(column)
+			}
+			builder.WriteString(") ")
+		}
+
+		// the partial unique index inferred as arbiter
+		if len(onConflict.TargetWhere.Exprs) > 0 {
+			builder.WriteString("WHERE ")
+			onConflict.TargetWhere.Build(builder)
+			builder.WriteByte(' ')
+		}
+	}
+
+	if onConflict.DoNothing || len(onConflict.DoUpdates) == 0 {
+		builder.WriteString("DO NOTHING")
+		return
+	}
+
+	builder.WriteString("DO UPDATE SET ")
+	onConflict.DoUpdates.Build(builder)
+	if len(onConflict.Where.Exprs) > 0 {
+		builder.WriteString(" WHERE ")
+		onConflict.Where.Build(builder)
+	}
+}
+
+// buildOnConflictClause builds the `ON CONFLICT` clause of an OnConflict unwrapped by unwrapOnConflict, the clauses of gorm
+// are built by gorm
+func buildOnConflictClause(c clause.Clause, builder clause.Builder) {
+	onConflict, ok := c.Expression.(clause.OnConflict)
+	if !ok {
+		c.Builder = nil
+		c.Build(builder)
+		return
+	}
+	builder.WriteString("ON CONFLICT ")
+	buildOnConflict(onConflict, builder)
+}
+
+// unwrapOnConflict replaces OnConflict by its gorm clause, so gorm expands UpdateAll and skips the conflicting rows of DoNothing,
+// the clause keeps being built as an OnConflict, and returns the InsertedColumn as `xmax = 0`
+func unwrapOnConflict(returning bool) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Error != nil {
+			return
+		}
+		c, ok := db.Statement.Clauses["ON CONFLICT"]
+		if !ok {
+			return
+		}
+		onConflict, ok := c.Expression.(OnConflict)
+		if !ok {
+			return
+		}
+		c.Expression, c.Builder = onConflict.OnConflict, buildOnConflictClause
+		db.Statement.Clauses["ON CONFLICT"] = c
+
+		if !returning || onConflict.InsertedColumn == "" {
+			return
+		}
+		inserted := clause.Column{Name: "(xmax = 0) AS " + db.Statement.Quote(onConflict.InsertedColumn), Raw: true}
+
+		current, ok := db.Statement.Clauses["RETURNING"]
+		if !ok {
+			// gorm returns the fields with a default value unless there is a RETURNING clause already
+			var columns []clause.Column
+			if db.Statement.Schema != nil {
+				for _, field := range db.Statement.Schema.FieldsWithDefaultDBValue {
+					columns = append(columns, clause.Column{Name: field.DBName})
+				}
+			}
+			db.Statement.AddClause(clause.Returning{Columns: append(columns, inserted)})
+			return
+		}
+		if existing, ok := current.Expression.(clause.Returning); ok && len(existing.Columns) == 0 {
+			// RETURNING *
+			current.Expression = clause.Returning{Columns: []clause.Column{{Name: "*", Raw: true}, inserted}}
+			db.Statement.Clauses["RETURNING"] = current
+			return
+		}
+		db.Statement.AddClause(clause.Returning{Columns: []clause.Column{inserted}})
+	}
+}
+
+func registerOnConflictCallbacks(db *gorm.DB, returning bool) error {
+	return db.Callback().Create().Before("gorm:create").After("postgres:generated_columns").Register("postgres:on_conflict", unwrapOnConflict(returning))
+}
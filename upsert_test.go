@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"testing"
+
+	"gorm.io/gorm/clause"
+)
+
+type upsertUser struct {
+	ID       uint64
+	Email    string
+	Name     string
+	Inserted bool `gorm:"->;-:migration"`
+}
+
+func TestOnConflict(t *testing.T) {
+	tx := dryRunDB(t)
+
+	tests := []struct {
+		name       string
+		onConflict clause.Expression
+		want       string
+	}{
+		{
+			name: "it should update with a condition and return whether the row was inserted",
+			onConflict: OnConflict{
+				OnConflict: clause.OnConflict{
+					Columns:     []clause.Column{{Name: "email"}},
+					TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "name IS NOT NULL"}}},
+					DoUpdates:   clause.Set{{Column: clause.Column{Name: "name"}, Value: Excluded("name")}},
+					Where:       clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "? IS DISTINCT FROM ?", Vars: []interface{}{clause.Column{Table: "upsert_users", Name: "name"}, Excluded("name")}}}},
+				},
+				InsertedColumn: "inserted",
+			},
+			want: `INSERT INTO "upsert_users" ("email","name") VALUES ($1,$2) ON CONFLICT ("email") WHERE name IS NOT NULL DO UPDATE SET "name"="excluded"."name" WHERE "upsert_users"."name" IS DISTINCT FROM "excluded"."name" RETURNING "id",(xmax = 0) AS "inserted"`,
+		},
+		{
+			name:       "it should quote the constraint and do nothing",
+			onConflict: OnConflict{OnConflict: clause.OnConflict{OnConstraint: "upsert_users_email_key", DoNothing: true}},
+			want:       `INSERT INTO "upsert_users" ("email","name") VALUES ($1,$2) ON CONFLICT ON CONSTRAINT "upsert_users_email_key" DO NOTHING RETURNING "id"`,
+		},
+		{
+			name:       "it should expand the update of all columns",
+			onConflict: OnConflict{OnConflict: clause.OnConflict{UpdateAll: true}, InsertedColumn: "inserted"},
+			want:       `INSERT INTO "upsert_users" ("email","name") VALUES ($1,$2) ON CONFLICT ("id") DO UPDATE SET "email"="excluded"."email","name"="excluded"."name" RETURNING "id",(xmax = 0) AS "inserted"`,
+		},
+		{
+			name:       "it should build the conflict clause of gorm",
+			onConflict: clause.OnConflict{Columns: []clause.Column{{Name: "email"}}, DoUpdates: clause.AssignmentColumns([]string{"name"})},
+			want:       `INSERT INTO "upsert_users" ("email","name") VALUES ($1,$2) ON CONFLICT ("email") DO UPDATE SET "name"="excluded"."name" RETURNING "id"`,
+		},
+		{
+			name:       "it should keep the constraint of the conflict clause of gorm as written",
+			onConflict: clause.OnConflict{OnConstraint: "upsert_users_email_key", DoNothing: true},
+			want:       `INSERT INTO "upsert_users" ("email","name") VALUES ($1,$2) ON CONFLICT ON CONSTRAINT upsert_users_email_key DO NOTHING RETURNING "id"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := upsertUser{Email: "jinzhu@example.org", Name: "jinzhu"}
+			if sql := tx.Clauses(tt.onConflict).Create(&user).Statement.SQL.String(); sql != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, sql)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"gorm.io/gorm/clause"
+)
+
+// Using the `USING` clause of a DELETE, joining the deleted rows with the rows of other tables referenced by the conditions
+//
+//	db.Clauses(postgres.Using{Tables: []clause.Table{{Name: "orders"}}}).
+//		Where("orders.user_id = users.id AND orders.state = ?", "void").Delete(&User{})
+type Using struct {
+	Tables []clause.Table
+}
+
+// Name the name of the clause
+func (Using) Name() string {
+	return "USING"
+}
+
+// Build builds the tables of the clause
+func (using Using) Build(builder clause.Builder) {
+	for idx, table := range using.Tables {
+		if idx > 0 {
+			builder.WriteByte(',')
+		}
+		builder.WriteQuoted(table)
+	}
+}
+
+// MergeClause merges the tables of the clauses
+func (using Using) MergeClause(c *clause.Clause) {
+	if v, ok := c.Expression.(Using); ok {
+		tables := make([]clause.Table, len(v.Tables), len(v.Tables)+len(using.Tables))
+		copy(tables, v.Tables)
+		using.Tables = append(tables, using.Tables...)
+	}
+	c.Expression = using
+}
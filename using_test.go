@@ -0,0 +1,22 @@
+package postgres
+
+import (
+	"testing"
+
+	"gorm.io/gorm/clause"
+)
+
+type usingUser struct {
+	ID   uint64
+	Name string
+}
+
+func TestUsing(t *testing.T) {
+	tx := dryRunDB(t)
+
+	stmt := tx.Clauses(Using{Tables: []clause.Table{{Name: "orders"}}}, Using{Tables: []clause.Table{{Name: "app.payments", Alias: "p"}}}).
+		Where("orders.user_id = using_users.id AND p.order_id = orders.id AND orders.state = ?", "void").Delete(&usingUser{}).Statement
+	if want := `DELETE FROM "using_users" USING "orders","app"."payments" "p" WHERE orders.user_id = using_users.id AND p.order_id = orders.id AND orders.state = $1`; stmt.SQL.String() != want {
+		t.Errorf("expected %v, got %v", want, stmt.SQL.String())
+	}
+}
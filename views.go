@@ -0,0 +1,205 @@
+package postgres
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+// View a model backed by a view, AutoMigrate creates or replaces the view with the query instead of creating a table
+//
+//	func (ActiveUser) ViewQuery(tx *gorm.DB) *gorm.DB {
+//		return tx.Model(&User{}).Where("active")
+//	}
+type View interface {
+	ViewQuery(tx *gorm.DB) *gorm.DB
+}
+
+// MaterializedView a model backed by a materialized view, AutoMigrate creates the materialized view when it is missing and its indexes,
+// and drops and recreates it when its definition changed, repopulating it
+type MaterializedView interface {
+	MaterializedViewQuery(tx *gorm.DB) *gorm.DB
+}
+
+// MaterializedViewOption options of CreateMaterializedView
+type MaterializedViewOption struct {
+	// Query is the required defining query
+	Query *gorm.DB
+	// Replace drops the materialized view first when it exists
+	Replace bool
+	// WithNoData creates the materialized view unpopulated, it can't be queried before it is refreshed
+	WithNoData bool
+}
+
+// declaredViewName the temporary view deparsing the declared query of a materialized view
+const declaredViewName = "postgres_declared_view"
+
+// errDiscardDeclaredView rolls back the transaction creating the declared view
+var errDiscardDeclaredView = errors.New("discard the declared view")
+
+// CreateView creates the view name with `CREATE [OR REPLACE] VIEW`, the query is inlined as views can't have bind parameters
+func (m Migrator) CreateView(name string, option gorm.ViewOption) error {
+	if option.Query == nil {
+		return gorm.ErrSubQueryRequired
+	}
+
+	createViewSQL := "CREATE "
+	if option.Replace {
+		createViewSQL += "OR REPLACE "
+	}
+	createViewSQL += "VIEW ? AS ?"
+	if option.CheckOption != "" {
+		createViewSQL += " " + option.CheckOption
+	}
+	return m.DB.Exec(m.inlineSQL(createViewSQL, clause.Table{Name: name}, option.Query)).Error
+}
+
+// HasView returns whether the view name exists in the current schema
+func (m Migrator) HasView(name string) bool {
+	return m.hasRelation(name, "v")
+}
+
+// GetViews returns the views of the current schema
+func (m Migrator) GetViews() ([]string, error) {
+	return m.getRelations("v")
+}
+
+// CreateMaterializedView creates the materialized view name
+//
+//	db.Migrator().(postgres.Migrator).CreateMaterializedView("daily_sales", postgres.MaterializedViewOption{
+//		Query: db.Model(&Order{}).Select("date_trunc('day', created_at) AS day, sum(amount) AS amount").Group("day"),
+//	})
+func (m Migrator) CreateMaterializedView(name string, option MaterializedViewOption) error {
+	if option.Query == nil {
+		return gorm.ErrSubQueryRequired
+	}
+
+	if option.Replace {
+		if err := m.DropMaterializedView(name); err != nil {
+			return err
+		}
+	}
+
+	createViewSQL := "CREATE MATERIALIZED VIEW IF NOT EXISTS ? AS ?"
+	if option.WithNoData {
+		createViewSQL += " WITH NO DATA"
+	} else {
+		createViewSQL += " WITH DATA"
+	}
+	return m.DB.Exec(m.inlineSQL(createViewSQL, clause.Table{Name: name}, option.Query)).Error
+}
+
+// RefreshMaterializedView refreshes the materialized view name,
+// concurrently doesn't block reads but requires a unique index on the materialized view
+func (m Migrator) RefreshMaterializedView(name string, concurrently bool) error {
+	refreshSQL := "REFRESH MATERIALIZED VIEW "
+	if concurrently {
+		refreshSQL += "CONCURRENTLY "
+	}
+	return m.DB.Exec(refreshSQL+"?", clause.Table{Name: name}).Error
+}
+
+// DropMaterializedView drops the materialized view name
+func (m Migrator) DropMaterializedView(name string) error {
+	return m.DB.Exec("DROP MATERIALIZED VIEW IF EXISTS ?", clause.Table{Name: name}).Error
+}
+
+// HasMaterializedView returns whether the materialized view name exists in the current schema
+func (m Migrator) HasMaterializedView(name string) bool {
+	return m.hasRelation(name, "m")
+}
+
+// GetMaterializedViews returns the materialized views of the current schema
+func (m Migrator) GetMaterializedViews() ([]string, error) {
+	return m.getRelations("m")
+}
+
+// migrateView migrates value when it is backed by a view or a materialized view, returning whether it is
+func (m Migrator) migrateView(value interface{}) (migrated bool, err error) {
+	switch view := value.(type) {
+	case View:
+		return true, m.RunWithValue(value, func(stmt *gorm.Statement) error {
+			return m.CreateView(stmt.Table, gorm.ViewOption{Replace: true, Query: view.ViewQuery(m.DB.Session(&gorm.Session{NewDB: true}))})
+		})
+	case MaterializedView:
+		return true, m.RunWithValue(value, func(stmt *gorm.Statement) error {
+			query := view.MaterializedViewQuery(m.DB.Session(&gorm.Session{NewDB: true}))
+			if !m.HasMaterializedView(stmt.Table) {
+				if err := m.CreateMaterializedView(stmt.Table, MaterializedViewOption{Query: query}); err != nil {
+					return err
+				}
+			} else if changed, err := m.materializedViewChanged(stmt.Table, query); err != nil {
+				return err
+			} else if changed {
+				if err := m.CreateMaterializedView(stmt.Table, MaterializedViewOption{Query: query, Replace: true}); err != nil {
+					return err
+				}
+			}
+
+			if stmt.Schema != nil {
+				for _, idx := range stmt.Schema.ParseIndexes() {
+					if !m.HasIndex(value, idx.Name) {
+						if err := m.CreateIndex(value, idx.Name); err != nil {
+							return err
+						}
+					}
+				}
+			}
+			return nil
+		})
+	}
+	return false, nil
+}
+
+// materializedViewChanged returns whether the definition of the materialized view name differs from query, query is deparsed by the
+// database through a temporary view rolled back afterwards, as the catalog doesn't keep the definition as written
+func (m Migrator) materializedViewChanged(name string, query *gorm.DB) (changed bool, err error) {
+	err = m.RunWithValue(name, func(stmt *gorm.Statement) error {
+		currentSchema, curTable := m.CurrentSchema(stmt, stmt.Table)
+		return m.queryTx().Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(m.inlineSQL("CREATE TEMPORARY VIEW ? AS ?", clause.Table{Name: declaredViewName}, query)).Error; err != nil {
+				return err
+			}
+			if err := tx.Raw(
+				"SELECT pg_get_viewdef(c.oid, true) <> pg_get_viewdef(to_regclass(?), true) FROM pg_catalog.pg_class c JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = ? AND c.relname = ? AND c.relkind = 'm'",
+				"pg_temp."+declaredViewName, currentSchema, curTable,
+			).Scan(&changed).Error; err != nil {
+				return err
+			}
+			return errDiscardDeclaredView
+		})
+	})
+	if errors.Is(err, errDiscardDeclaredView) {
+		err = nil
+	}
+	return
+}
+
+func (m Migrator) hasRelation(name, relkind string) bool {
+	var count int64
+	m.RunWithValue(name, func(stmt *gorm.Statement) error {
+		currentSchema, curTable := m.CurrentSchema(stmt, stmt.Table)
+		return m.queryRaw(
+			"SELECT count(*) FROM pg_catalog.pg_class c JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = ? AND c.relname = ? AND c.relkind = ?",
+			currentSchema, curTable, relkind,
+		).Scan(&count).Error
+	})
+	return count > 0
+}
+
+func (m Migrator) getRelations(relkind string) (names []string, err error) {
+	currentSchema, _ := m.CurrentSchema(m.DB.Statement, "")
+	return names, m.queryRaw(
+		"SELECT c.relname FROM pg_catalog.pg_class c JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = ? AND c.relkind = ?",
+		currentSchema, relkind,
+	).Scan(&names).Error
+}
+
+// inlineSQL renders sql with its vars inlined, for DDL statements that can't have bind parameters
+func (m Migrator) inlineSQL(sql string, vars ...interface{}) string {
+	return m.DB.Session(&gorm.Session{NewDB: true, Logger: logger.Discard}).ToSQL(func(tx *gorm.DB) *gorm.DB {
+		return tx.Exec(sql, vars...)
+	})
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type viewUser struct {
+	ID     uint64
+	Name   string
+	Active bool
+}
+
+type dailyViewUser struct {
+	Name  string
+	Count int64
+}
+
+func (dailyViewUser) MaterializedViewQuery(tx *gorm.DB) *gorm.DB {
+	return tx.Model(&viewUser{}).Select("name, count(*) AS count").Where("active").Group("name")
+}
+
+func TestMigrator_migrateView_materialized(t *testing.T) {
+	create := `CREATE MATERIALIZED VIEW IF NOT EXISTS "daily_view_users" AS SELECT name, count(*) AS count FROM "view_users" WHERE active GROUP BY "name" WITH DATA`
+	tests := []struct {
+		name    string
+		exists  bool
+		changed bool
+		want    []string
+	}{
+		{name: "it should create the missing materialized view", want: []string{create}},
+		{
+			name:    "it should recreate the materialized view when its definition changed",
+			exists:  true,
+			changed: true,
+			want:    []string{`DROP MATERIALIZED VIEW IF EXISTS "daily_view_users"`, create},
+		},
+		{name: "it should keep the unchanged materialized view", exists: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, connector := stubDB(t, func(query string) ([]string, [][]driver.Value, error) {
+				switch {
+				case strings.Contains(query, "count(*) FROM pg_catalog.pg_class"):
+					count := int64(0)
+					if tt.exists {
+						count = 1
+					}
+					return []string{"count"}, [][]driver.Value{{count}}, nil
+				case strings.Contains(query, "pg_get_viewdef"):
+					return []string{"changed"}, [][]driver.Value{{tt.changed}}, nil
+				}
+				return nil, nil, nil
+			})
+			m := db.Migrator().(Migrator)
+
+			if migrated, err := m.migrateView(&dailyViewUser{}); !migrated || err != nil {
+				t.Fatalf("migrateView() = %v, %v, want true, nil", migrated, err)
+			}
+			var executed []string
+			for _, statement := range connector.statements() {
+				if strings.HasPrefix(statement, "CREATE MATERIALIZED VIEW") || strings.HasPrefix(statement, "DROP MATERIALIZED VIEW") {
+					executed = append(executed, statement)
+				}
+			}
+			if !reflect.DeepEqual(executed, tt.want) {
+				t.Errorf("migrateView() executed %q, want %q", executed, tt.want)
+			}
+			if tt.exists && !strings.Contains(strings.Join(connector.statements(), "\n"), `CREATE TEMPORARY VIEW "postgres_declared_view"`) {
+				t.Errorf("migrateView() didn't deparse the declared query, executed %q", connector.statements())
+			}
+		})
+	}
+}
+
+func TestMigrator_inlineSQL(t *testing.T) {
+	db := testDB(t, Config{})
+	m := db.Migrator().(Migrator)
+
+	query := db.Model(&viewUser{}).Select("id", "name").Where("active = ? AND name <> ?", true, "it's")
+	got := m.inlineSQL("CREATE OR REPLACE VIEW ? AS ?", clause.Table{Name: "active_users"}, query)
+	want := `CREATE OR REPLACE VIEW "active_users" AS SELECT "id","name" FROM "view_users" WHERE active = true AND name <> 'it''s'`
+	if got != want {
+		t.Errorf("inlineSQL() = %v, want %v", got, want)
+	}
+}